@@ -62,9 +62,39 @@ func NewPhysicalMachineCommand(logger logr.Logger) (*cobra.Command, error) {
 		os.Exit(1)
 	}
 
+	exportCACmd, err := physicalmachine.NewPhysicalMachineExportCACmd(logger)
+	if err != nil {
+		logger.Error(err, "failed to initialize cmd",
+			"cmd", "physicalmachine-export-ca",
+			"errorVerbose", fmt.Sprintf("%+v", err),
+		)
+		os.Exit(1)
+	}
+
+	packagePKICmd, err := physicalmachine.NewPhysicalMachinePackagePKICmd(logger)
+	if err != nil {
+		logger.Error(err, "failed to initialize cmd",
+			"cmd", "physicalmachine-package-pki",
+			"errorVerbose", fmt.Sprintf("%+v", err),
+		)
+		os.Exit(1)
+	}
+
+	extractPKICmd, err := physicalmachine.NewPhysicalMachineExtractPKICmd(logger)
+	if err != nil {
+		logger.Error(err, "failed to initialize cmd",
+			"cmd", "physicalmachine-extract-pki",
+			"errorVerbose", fmt.Sprintf("%+v", err),
+		)
+		os.Exit(1)
+	}
+
 	physicalMachineCmd.AddCommand(initCmd)
 	physicalMachineCmd.AddCommand(generateCmd)
 	physicalMachineCmd.AddCommand(createCmd)
+	physicalMachineCmd.AddCommand(exportCACmd)
+	physicalMachineCmd.AddCommand(packagePKICmd)
+	physicalMachineCmd.AddCommand(extractPKICmd)
 
 	return physicalMachineCmd, nil
 }
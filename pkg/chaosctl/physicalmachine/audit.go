@@ -0,0 +1,124 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RotationExpiryThreshold is the remaining validity under which PlanCARotation reports a cert as expiring
+	RotationExpiryThreshold = time.Hour * 24 * 30
+	// estimatedReissueDuration is the rough time to reissue a cert and distribute it to a physical machine
+	estimatedReissueDuration = time.Second * 10
+)
+
+// FindSharedKeys inspects the certificates (*.crt) in pkiPath and returns the public keys which are
+// used by more than one certificate, mapping the SHA-256 fingerprint of the key to the cert files using it.
+// An empty map means that no key is shared.
+func FindSharedKeys(pkiPath string) (map[string][]string, error) {
+	certFiles, certs, err := loadCertsInDir(pkiPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keyToCerts := make(map[string][]string)
+	for i, cert := range certs {
+		fingerprint := publicKeyFingerprint(cert)
+		keyToCerts[fingerprint] = append(keyToCerts[fingerprint], certFiles[i])
+	}
+
+	for fingerprint, files := range keyToCerts {
+		if len(files) < 2 {
+			delete(keyToCerts, fingerprint)
+		}
+	}
+	return keyToCerts, nil
+}
+
+// RotationPlan describes what rotating the CA would change in a PKI directory
+type RotationPlan struct {
+	// Reissue lists the leaf cert files which are not signed by the new CA
+	Reissue []string
+	// Expiring lists the leaf cert files which expire within RotationExpiryThreshold
+	Expiring []string
+	// EstimatedDuration is a rough estimate of the time to reissue and distribute the certs in Reissue
+	EstimatedDuration time.Duration
+}
+
+// PlanCARotation inspects the certificates (*.crt) in pkiPath and plans the rotation to newCA,
+// it's a dry run and doesn't change anything on disk.
+// CA certificates in the directory are not considered as leaves to reissue.
+func PlanCARotation(pkiPath string, newCA *x509.Certificate) (RotationPlan, error) {
+	var plan RotationPlan
+
+	certFiles, certs, err := loadCertsInDir(pkiPath)
+	if err != nil {
+		return plan, err
+	}
+
+	for i, cert := range certs {
+		if cert.IsCA {
+			continue
+		}
+		if err := cert.CheckSignatureFrom(newCA); err != nil {
+			plan.Reissue = append(plan.Reissue, certFiles[i])
+		}
+		if CertExpiresWithin(cert, RotationExpiryThreshold) {
+			plan.Expiring = append(plan.Expiring, certFiles[i])
+		}
+	}
+	plan.EstimatedDuration = time.Duration(len(plan.Reissue)) * estimatedReissueDuration
+
+	return plan, nil
+}
+
+// loadCertsInDir parses the certificates (*.crt) in pkiPath, sorted by file name
+func loadCertsInDir(pkiPath string) ([]string, []*x509.Certificate, error) {
+	certFiles, err := filepath.Glob(pathForCert(pkiPath, "*"))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "list certificates in %s failed", pkiPath)
+	}
+	sort.Strings(certFiles)
+
+	certs := make([]*x509.Certificate, 0, len(certFiles))
+	for _, certFile := range certFiles {
+		data, err := ioutil.ReadFile(certFile)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot read cert file %s", certFile)
+		}
+		cert, err := ParseCert(data)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "parse cert file %s failed", certFile)
+		}
+		certs = append(certs, cert)
+	}
+	return certFiles, certs, nil
+}
+
+// publicKeyFingerprint returns the hex encoded SHA-256 of the certificate's SubjectPublicKeyInfo
+func publicKeyFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/x509"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestFindSharedKeys(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	pkiPath := t.TempDir()
+
+	sharedKey, err := NewPrivateKey(x509.ECDSA, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, name := range []string{"host-1", "host-2"} {
+		cert, err := NewSignedCert(sharedKey, caCert, caKey, false, CertOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(WriteCert(pkiPath, name, cert)).To(Succeed())
+	}
+	g.Expect(WriteCert(pkiPath, "ca", caCert)).To(Succeed())
+
+	shared, err := FindSharedKeys(pkiPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(shared).To(HaveLen(1))
+	for _, files := range shared {
+		g.Expect(files).To(Equal([]string{pathForCert(pkiPath, "host-1"), pathForCert(pkiPath, "host-2")}))
+	}
+
+	shared, err = FindSharedKeys(t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(shared).To(BeEmpty())
+}
+
+func TestPlanCARotation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	oldCA, oldCAKey := newTestCA(g, "old-ca")
+	newCA, newCAKey := newTestCA(g, "new-ca")
+	pkiPath := t.TempDir()
+
+	oldLeaf, _ := newTestCert(g, "host-1", false, oldCA, oldCAKey)
+	newLeaf, _ := newTestCert(g, "host-2", false, newCA, newCAKey)
+	g.Expect(WriteCert(pkiPath, "ca", oldCA)).To(Succeed())
+	g.Expect(WriteCert(pkiPath, "host-1", oldLeaf)).To(Succeed())
+	g.Expect(WriteCert(pkiPath, "host-2", newLeaf)).To(Succeed())
+
+	plan, err := PlanCARotation(pkiPath, newCA)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(plan.Reissue).To(Equal([]string{pathForCert(pkiPath, "host-1")}))
+	// the test certs are valid for a day only
+	g.Expect(plan.Expiring).To(Equal([]string{pathForCert(pkiPath, "host-1"), pathForCert(pkiPath, "host-2")}))
+	g.Expect(plan.EstimatedDuration).To(Equal(estimatedReissueDuration))
+
+	// planning doesn't change anything
+	files, _, err := loadCertsInDir(pkiPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(files).To(HaveLen(3))
+}
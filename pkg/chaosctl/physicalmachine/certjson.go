@@ -0,0 +1,160 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CertJSON is the metadata of a certificate returned by APIs,
+// the serial number is encoded as a hex string and the validity as RFC3339 timestamps
+type CertJSON struct {
+	Subject        string
+	DNSNames       []string
+	IPAddresses    []net.IP
+	URIs           []string
+	EmailAddresses []string
+	SerialNumber   *big.Int
+	NotBefore      time.Time
+	NotAfter       time.Time
+	KeyType        x509.PublicKeyAlgorithm
+	Fingerprint    string
+}
+
+type certJSONWire struct {
+	Subject        string   `json:"subject"`
+	DNSNames       []string `json:"dns_names,omitempty"`
+	IPAddresses    []string `json:"ip_addresses,omitempty"`
+	URIs           []string `json:"uris,omitempty"`
+	EmailAddresses []string `json:"email_addresses,omitempty"`
+	SerialNumber   string   `json:"serial_number"`
+	NotBefore      string   `json:"not_before"`
+	NotAfter       string   `json:"not_after"`
+	KeyType        string   `json:"key_type"`
+	Fingerprint    string   `json:"fingerprint"`
+}
+
+// ToCertJSON converts the certificate to CertJSON
+func ToCertJSON(cert *x509.Certificate) CertJSON {
+	uris := make([]string, 0, len(cert.URIs))
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+	if len(uris) == 0 {
+		uris = nil
+	}
+
+	return CertJSON{
+		Subject:        cert.Subject.String(),
+		DNSNames:       cert.DNSNames,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           uris,
+		EmailAddresses: cert.EmailAddresses,
+		SerialNumber:   cert.SerialNumber,
+		NotBefore:      cert.NotBefore,
+		NotAfter:       cert.NotAfter,
+		KeyType:        cert.PublicKeyAlgorithm,
+		Fingerprint:    CertFingerprintSHA256(cert),
+	}
+}
+
+func (c CertJSON) MarshalJSON() ([]byte, error) {
+	wire := certJSONWire{
+		Subject:        c.Subject,
+		DNSNames:       c.DNSNames,
+		URIs:           c.URIs,
+		EmailAddresses: c.EmailAddresses,
+		NotBefore:      c.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:       c.NotAfter.UTC().Format(time.RFC3339),
+		KeyType:        c.KeyType.String(),
+		Fingerprint:    c.Fingerprint,
+	}
+	for _, ip := range c.IPAddresses {
+		wire.IPAddresses = append(wire.IPAddresses, ip.String())
+	}
+	if c.SerialNumber != nil {
+		wire.SerialNumber = c.SerialNumber.Text(16)
+	}
+	return json.Marshal(wire)
+}
+
+func (c *CertJSON) UnmarshalJSON(data []byte) error {
+	var wire certJSONWire
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	var ips []net.IP
+	for _, s := range wire.IPAddresses {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return errors.Errorf("invalid ip address %q", s)
+		}
+		ips = append(ips, ip)
+	}
+
+	var serial *big.Int
+	if len(wire.SerialNumber) != 0 {
+		var ok bool
+		if serial, ok = new(big.Int).SetString(wire.SerialNumber, 16); !ok {
+			return errors.Errorf("invalid hex serial number %q", wire.SerialNumber)
+		}
+	}
+
+	notBefore, err := time.Parse(time.RFC3339, wire.NotBefore)
+	if err != nil {
+		return errors.Wrap(err, "parse not_before failed")
+	}
+	notAfter, err := time.Parse(time.RFC3339, wire.NotAfter)
+	if err != nil {
+		return errors.Wrap(err, "parse not_after failed")
+	}
+
+	keyType, err := parsePublicKeyAlgorithm(wire.KeyType)
+	if err != nil {
+		return err
+	}
+
+	*c = CertJSON{
+		Subject:        wire.Subject,
+		DNSNames:       wire.DNSNames,
+		IPAddresses:    ips,
+		URIs:           wire.URIs,
+		EmailAddresses: wire.EmailAddresses,
+		SerialNumber:   serial,
+		NotBefore:      notBefore,
+		NotAfter:       notAfter,
+		KeyType:        keyType,
+		Fingerprint:    wire.Fingerprint,
+	}
+	return nil
+}
+
+// parsePublicKeyAlgorithm is the reverse of x509.PublicKeyAlgorithm.String
+func parsePublicKeyAlgorithm(s string) (x509.PublicKeyAlgorithm, error) {
+	for _, algo := range []x509.PublicKeyAlgorithm{x509.RSA, x509.DSA, x509.ECDSA, x509.Ed25519} {
+		if algo.String() == s {
+			return algo, nil
+		}
+	}
+	return x509.UnknownPublicKeyAlgorithm, errors.Errorf("unknown key type %q", s)
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCertJSON(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	cert, _, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	data, err := json.Marshal(ToCertJSON(cert))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var fields map[string]interface{}
+	g.Expect(json.Unmarshal(data, &fields)).To(Succeed())
+	g.Expect(fields["subject"]).To(Equal("CN=chaosd.chaos-mesh.org"))
+	g.Expect(fields["dns_names"]).To(Equal([]interface{}{"chaosd.chaos-mesh.org", "localhost"}))
+	g.Expect(fields["serial_number"]).To(Equal(cert.SerialNumber.Text(16)))
+	g.Expect(fields["not_before"]).To(Equal(cert.NotBefore.UTC().Format(time.RFC3339)))
+	g.Expect(fields["not_after"]).To(Equal(cert.NotAfter.UTC().Format(time.RFC3339)))
+	g.Expect(fields["key_type"]).To(Equal("RSA"))
+
+	sum := sha256.Sum256(cert.Raw)
+	fingerprint := fields["fingerprint"].(string)
+	g.Expect(strings.ReplaceAll(fingerprint, ":", "")).To(Equal(strings.ToUpper(fmt.Sprintf("%x", sum))))
+
+	var decoded CertJSON
+	g.Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+	g.Expect(decoded.SerialNumber.Cmp(cert.SerialNumber)).To(Equal(0))
+	g.Expect(decoded.NotAfter.Equal(cert.NotAfter)).To(BeTrue())
+	g.Expect(decoded.KeyType).To(Equal(x509.RSA))
+	g.Expect(decoded.Fingerprint).To(Equal(fingerprint))
+}
+
+func TestCertJSONSerialIsHex(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	data, err := json.Marshal(CertJSON{
+		SerialNumber: big.NewInt(255),
+		NotBefore:    time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC),
+		KeyType:      x509.ECDSA,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(ContainSubstring(`"serial_number":"ff"`))
+	g.Expect(string(data)).To(ContainSubstring(`"not_before":"2021-12-01T00:00:00Z"`))
+	g.Expect(string(data)).To(ContainSubstring(`"key_type":"ECDSA"`))
+
+	var decoded CertJSON
+	g.Expect(json.Unmarshal([]byte(`{"serial_number":"zz","not_before":"2021-12-01T00:00:00Z","not_after":"2021-12-01T00:00:00Z","key_type":"RSA"}`), &decoded)).ToNot(Succeed())
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CertFingerprintSHA256 returns the colon-separated hex of the SHA-256 over the DER of the certificate,
+// which is the same as `openssl x509 -fingerprint -sha256`
+func CertFingerprintSHA256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	hexBytes := make([]string, 0, len(sum))
+	for _, b := range sum {
+		hexBytes = append(hexBytes, fmt.Sprintf("%02X", b))
+	}
+	return strings.Join(hexBytes, ":")
+}
+
+// CertSummary returns a human-readable multi-line description of the certificate
+func CertSummary(cert *x509.Certificate) string {
+	ips := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	serial := "<none>"
+	if cert.SerialNumber != nil {
+		serial = cert.SerialNumber.Text(16)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Common Name:  %s\n", cert.Subject.CommonName)
+	fmt.Fprintf(&b, "DNS Names:    %s\n", joinOrNone(cert.DNSNames))
+	fmt.Fprintf(&b, "IP Addresses: %s\n", joinOrNone(ips))
+	fmt.Fprintf(&b, "Serial:       %s\n", serial)
+	fmt.Fprintf(&b, "Not Before:   %s\n", cert.NotBefore.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Not After:    %s\n", cert.NotAfter.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Is CA:        %t\n", cert.IsCA)
+	fmt.Fprintf(&b, "SHA-256:      %s\n", CertFingerprintSHA256(cert))
+	return b.String()
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCertSummary(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	ips, err := ParseIPAddresses([]string{"10.0.3.17"})
+	g.Expect(err).ToNot(HaveOccurred())
+	cert, _, err := NewCertAndKey(caCert, caKey, CertOptions{
+		Subject:     pkix.Name{CommonName: "host-1.example.org"},
+		IPAddresses: ips,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	fingerprint := CertFingerprintSHA256(cert)
+	sum := sha256.Sum256(cert.Raw)
+	g.Expect(fingerprint).To(HaveLen(len(sum)*3 - 1))
+	g.Expect(strings.ReplaceAll(fingerprint, ":", "")).To(Equal(strings.ToUpper(hex.EncodeToString(sum[:]))))
+	g.Expect(ToCertJSON(cert).Fingerprint).To(Equal(fingerprint))
+
+	summary := CertSummary(cert)
+	g.Expect(strings.Split(strings.TrimSuffix(summary, "\n"), "\n")).To(Equal([]string{
+		"Common Name:  host-1.example.org",
+		"DNS Names:    chaosd.chaos-mesh.org, localhost",
+		"IP Addresses: 10.0.3.17",
+		"Serial:       " + cert.SerialNumber.Text(16),
+		"Not Before:   " + cert.NotBefore.UTC().Format(time.RFC3339),
+		"Not After:    " + cert.NotAfter.UTC().Format(time.RFC3339),
+		"Is CA:        false",
+		"SHA-256:      " + fingerprint,
+	}))
+
+	summary = CertSummary(caCert)
+	g.Expect(summary).To(ContainSubstring("Is CA:        true"))
+	g.Expect(summary).To(ContainSubstring("IP Addresses: <none>"))
+}
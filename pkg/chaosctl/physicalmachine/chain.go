@@ -0,0 +1,102 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"bytes"
+	"crypto/x509"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OrderChain reorders a bundle of certificates from the leaf to the root, by matching
+// the issuer of each certificate with the subject of the next one.
+// It returns an error if the certificates do not form exactly one chain.
+func OrderChain(certs []*x509.Certificate) ([]*x509.Certificate, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates to order")
+	}
+
+	// the leaf is the only certificate which is not the issuer of any other certificate
+	var leaf *x509.Certificate
+	for i, cert := range certs {
+		isIssuer := false
+		for j, other := range certs {
+			if i != j && issuedBy(other, cert) {
+				isIssuer = true
+				break
+			}
+		}
+		if isIssuer {
+			continue
+		}
+		if leaf != nil {
+			return nil, errors.Errorf("certificates do not form a single chain: both %q and %q look like leaves",
+				leaf.Subject.String(), cert.Subject.String())
+		}
+		leaf = cert
+	}
+	if leaf == nil {
+		return nil, errors.New("certificates do not form a single chain: no leaf found")
+	}
+
+	ordered := []*x509.Certificate{leaf}
+	used := map[*x509.Certificate]bool{leaf: true}
+	current := leaf
+	for len(ordered) < len(certs) {
+		var parent *x509.Certificate
+		for _, cert := range certs {
+			if used[cert] || !issuedBy(current, cert) {
+				continue
+			}
+			if parent != nil {
+				return nil, errors.Errorf("certificates do not form a single chain: %q has more than one issuer",
+					current.Subject.String())
+			}
+			parent = cert
+		}
+		if parent == nil {
+			return nil, errors.Errorf("certificates do not form a single chain: issuer of %q not found",
+				current.Subject.String())
+		}
+		ordered = append(ordered, parent)
+		used[parent] = true
+		current = parent
+	}
+
+	return ordered, nil
+}
+
+// ChainValidityWindow returns the intersection of the validity windows of all certificates in the chain,
+// which is the time range the chain is usable in.
+// If the windows don't overlap, the returned notBefore is after notAfter.
+func ChainValidityWindow(chain []*x509.Certificate) (notBefore, notAfter time.Time) {
+	for i, cert := range chain {
+		if i == 0 || cert.NotBefore.After(notBefore) {
+			notBefore = cert.NotBefore
+		}
+		if i == 0 || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+	return notBefore, notAfter
+}
+
+// issuedBy reports whether the issuer of cert matches the subject of issuer
+func issuedBy(cert, issuer *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, issuer.RawSubject)
+}
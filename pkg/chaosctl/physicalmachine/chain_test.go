@@ -0,0 +1,71 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestOrderChain(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	root, rootKey := newTestCA(g, "root")
+	intermediate, intermediateKey := newTestCert(g, "intermediate", true, root, rootKey)
+	leaf, _ := newTestCert(g, "leaf", false, intermediate, intermediateKey)
+
+	ordered, err := OrderChain([]*x509.Certificate{leaf, intermediate, root})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ordered).To(Equal([]*x509.Certificate{leaf, intermediate, root}))
+
+	ordered, err = OrderChain([]*x509.Certificate{root, intermediate, leaf})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ordered).To(Equal([]*x509.Certificate{leaf, intermediate, root}))
+
+	otherRoot, otherRootKey := newTestCA(g, "other-root")
+	otherLeaf, _ := newTestCert(g, "other-leaf", false, otherRoot, otherRootKey)
+	_, err = OrderChain([]*x509.Certificate{leaf, intermediate, root, otherLeaf, otherRoot})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("single chain"))
+
+	_, err = OrderChain(nil)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestChainValidityWindow(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ca := &x509.Certificate{
+		NotBefore: now.Add(-24 * time.Hour),
+		NotAfter:  now.Add(30 * 24 * time.Hour),
+	}
+	leaf := &x509.Certificate{
+		NotBefore: now,
+		NotAfter:  now.Add(365 * 24 * time.Hour),
+	}
+
+	notBefore, notAfter := ChainValidityWindow([]*x509.Certificate{leaf, ca})
+	g.Expect(notBefore).To(Equal(leaf.NotBefore))
+	g.Expect(notAfter).To(Equal(ca.NotAfter))
+
+	notBefore, notAfter = ChainValidityWindow(nil)
+	g.Expect(notBefore.IsZero()).To(BeTrue())
+	g.Expect(notAfter.IsZero()).To(BeTrue())
+}
@@ -0,0 +1,288 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"hash"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+	"k8s.io/client-go/util/keyutil"
+)
+
+const (
+	// EncryptedPrivateKeyBlockType is the pem.Block.Type of PKCS#8 encrypted private keys
+	EncryptedPrivateKeyBlockType = "ENCRYPTED PRIVATE KEY"
+	// pbkdf2Iterations is the PBKDF2 iteration count used when encrypting private keys
+	pbkdf2Iterations = 100000
+	pbkdf2SaltSize   = 16
+)
+
+// ErrKeyEncrypted is returned by ParsePrivateKey if the private key is encrypted
+var ErrKeyEncrypted = errors.New("key is encrypted, passphrase required")
+
+// the keys are encrypted with PBES2 (RFC 8018), the same as `openssl pkcs8 -topk8 -v2 aes-256-cbc`
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// EncodeEncryptedPrivateKeyPEM returns the private key as a PEM-encoded PKCS#8 encrypted private key,
+// which is encrypted by AES-256-CBC with a key derived from the passphrase by PBKDF2
+func EncodeEncryptedPrivateKeyPEM(key crypto.Signer, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to marshal private key to PEM")
+	}
+
+	salt := make([]byte, pbkdf2SaltSize)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := cryptorand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "generate salt failed")
+	}
+	if _, err := cryptorand.Read(iv); err != nil {
+		return nil, errors.Wrap(err, "generate iv failed")
+	}
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, salt, pbkdf2Iterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	encrypted := pkcs7Pad(der, aes.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	info, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algo:          pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  EncryptedPrivateKeyBlockType,
+		Bytes: info,
+	}), nil
+}
+
+// WriteEncryptedKey stores the given key at the given location, encrypted with the passphrase
+func WriteEncryptedKey(pkiPath, name string, key crypto.Signer, passphrase []byte) error {
+	if key == nil {
+		return errors.New("private key cannot be nil when writing to file")
+	}
+
+	privateKeyPath := pathForKey(pkiPath, name)
+	encoded, err := EncodeEncryptedPrivateKeyPEM(key, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(privateKeyPath, encoded, keyFileMode); err != nil {
+		return errors.Wrapf(err, "unable to write private key to file %s", privateKeyPath)
+	}
+
+	return nil
+}
+
+// ParseEncryptedPrivateKey parses the private key in data, decrypting it with the passphrase if it's encrypted.
+// It's the same as ParsePrivateKey if the passphrase is empty.
+func ParseEncryptedPrivateKey(data, passphrase []byte) (crypto.Signer, error) {
+	if len(passphrase) == 0 {
+		return ParsePrivateKey(data)
+	}
+
+	block := findPrivateKeyBlock(data)
+	if block == nil || !isEncryptedBlock(block) {
+		return ParsePrivateKey(data)
+	}
+	if block.Type != EncryptedPrivateKeyBlockType {
+		return nil, errors.New("legacy PEM encryption is not supported, please convert the key to an encrypted PKCS#8 key")
+	}
+
+	der, err := decryptPKCS8(block.Bytes, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	privKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.New("decrypt private key failed, the passphrase may be wrong")
+	}
+	return toSigner(privKey)
+}
+
+// findPrivateKeyBlock returns the first PEM block in data which could be a private key
+func findPrivateKeyBlock(data []byte) *pem.Block {
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil
+		}
+		switch block.Type {
+		case keyutil.RSAPrivateKeyBlockType, keyutil.ECPrivateKeyBlockType, keyutil.PrivateKeyBlockType, EncryptedPrivateKeyBlockType:
+			return block
+		}
+	}
+}
+
+// isEncryptedBlock reports whether the block is a PKCS#8 encrypted key or encrypted by the legacy RFC 1423 headers
+func isEncryptedBlock(block *pem.Block) bool {
+	return block.Type == EncryptedPrivateKeyBlockType || strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED")
+}
+
+func decryptPKCS8(data, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(data, &info); err != nil {
+		return nil, errors.Wrap(err, "parse encrypted private key failed")
+	}
+	if !info.Algo.Algorithm.Equal(oidPBES2) {
+		return nil, errors.Errorf("unsupported key encryption algorithm %s", info.Algo.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algo.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.Wrap(err, "parse PBES2 parameters failed")
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, errors.Errorf("unsupported key derivation function %s", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, errors.Wrap(err, "parse PBKDF2 parameters failed")
+	}
+	if kdfParams.IterationCount <= 0 {
+		return nil, errors.Errorf("invalid PBKDF2 iteration count %d", kdfParams.IterationCount)
+	}
+	var prf func() hash.Hash
+	switch {
+	// HMAC-SHA1 is the default PRF if it's absent
+	case len(kdfParams.PRF.Algorithm) == 0, kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, errors.Errorf("unsupported PBKDF2 PRF %s", kdfParams.PRF.Algorithm)
+	}
+
+	var keyLen int
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keyLen = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, errors.Errorf("unsupported key encryption scheme %s", params.EncryptionScheme.Algorithm)
+	}
+	if kdfParams.KeyLength != 0 && kdfParams.KeyLength != keyLen {
+		return nil, errors.Errorf("invalid PBKDF2 key length %d", kdfParams.KeyLength)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, errors.Wrap(err, "parse iv failed")
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.Errorf("invalid iv length %d", len(iv))
+	}
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted private key length")
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, kdfParams.Salt, kdfParams.IterationCount, keyLen, prf))
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+	decrypted, ok := pkcs7Unpad(decrypted, aes.BlockSize)
+	if !ok {
+		return nil, errors.New("decrypt private key failed, the passphrase may be wrong")
+	}
+	return decrypted, nil
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padding := blockSize - len(data)%blockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > blockSize || padding > len(data) {
+		return nil, false
+	}
+	if !bytes.Equal(data[len(data)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, false
+	}
+	return data[:len(data)-padding], true
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func TestEncryptedKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	passphrase := []byte("correct horse battery staple")
+	for _, keyType := range []x509.PublicKeyAlgorithm{x509.RSA, x509.ECDSA, x509.Ed25519} {
+		key, err := NewPrivateKey(keyType, KeyOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		pkiPath := t.TempDir()
+		g.Expect(WriteEncryptedKey(pkiPath, "test", key, passphrase)).To(Succeed())
+		info, err := os.Stat(pathForKey(pkiPath, "test"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(info.Mode().Perm()).To(Equal(keyFileMode))
+
+		data, err := ioutil.ReadFile(pathForKey(pkiPath, "test"))
+		g.Expect(err).ToNot(HaveOccurred())
+		block, _ := pem.Decode(data)
+		g.Expect(block).ToNot(BeNil())
+		g.Expect(block.Type).To(Equal(EncryptedPrivateKeyBlockType))
+
+		parsed, err := ParseEncryptedPrivateKey(data, passphrase)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(parsed.Public()).To(Equal(key.Public()))
+
+		_, err = ParsePrivateKey(data)
+		g.Expect(errors.Is(err, ErrKeyEncrypted)).To(BeTrue())
+		_, err = ParseEncryptedPrivateKey(data, nil)
+		g.Expect(errors.Is(err, ErrKeyEncrypted)).To(BeTrue())
+
+		_, err = ParseEncryptedPrivateKey(data, []byte("wrong passphrase"))
+		g.Expect(err).To(HaveOccurred())
+	}
+
+	// unencrypted keys are parsed regardless of the passphrase
+	key, err := NewPrivateKey(x509.ECDSA, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	plain, err := EncodePrivateKeyPEM(key)
+	g.Expect(err).ToNot(HaveOccurred())
+	parsed, err := ParseEncryptedPrivateKey(plain, passphrase)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(parsed.Public()).To(Equal(key.Public()))
+
+	_, err = EncodeEncryptedPrivateKeyPEM(key, nil)
+	g.Expect(err).To(HaveOccurred())
+
+	legacy := pem.EncodeToMemory(&pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: map[string]string{"Proc-Type": "4,ENCRYPTED", "DEK-Info": "AES-256-CBC,00000000000000000000000000000000"},
+		Bytes:   []byte("not really encrypted"),
+	})
+	_, err = ParsePrivateKey(legacy)
+	g.Expect(errors.Is(err, ErrKeyEncrypted)).To(BeTrue())
+	_, err = ParseEncryptedPrivateKey(legacy, passphrase)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("legacy"))
+}
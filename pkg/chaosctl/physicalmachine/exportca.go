@@ -0,0 +1,93 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/chaos-mesh/chaos-mesh/pkg/chaosctl/common"
+)
+
+type PhysicalMachineExportCAOptions struct {
+	logger             logr.Logger
+	chaosMeshNamespace string
+	caCertFile         string
+}
+
+func NewPhysicalMachineExportCACmd(logger logr.Logger) (*cobra.Command, error) {
+	exportCAOption := &PhysicalMachineExportCAOptions{
+		logger: logger,
+	}
+
+	exportCACmd := &cobra.Command{
+		Use:   `export-ca`,
+		Short: `Print the CA cert in the format expected by chaosd config`,
+		Long: `Print the CA cert in the format expected by chaosd config
+
+Examples:
+  # Print the CA cert stored in the Kubernetes cluster
+  chaosctl pm export-ca --chaos-mesh-namespace NAMESPACE
+
+  # Print the CA cert from a local file
+  chaosctl pm export-ca --cacert CACERT_FILE
+  `,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportCAOption.Run()
+		},
+	}
+	exportCACmd.PersistentFlags().StringVar(&exportCAOption.chaosMeshNamespace, "chaos-mesh-namespace", "chaos-testing", "namespace where chaos mesh installed")
+	exportCACmd.PersistentFlags().StringVar(&exportCAOption.caCertFile, "cacert", "", "file path to cacert file, if empty the CA cert is read from the Kubernetes cluster")
+	return exportCACmd, nil
+}
+
+func (o *PhysicalMachineExportCAOptions) Run() error {
+	var caCert *x509.Certificate
+	if len(o.caCertFile) != 0 {
+		certData, err := ioutil.ReadFile(o.caCertFile)
+		if err != nil {
+			return errors.Wrap(err, "cannot read cert file")
+		}
+		if caCert, err = ParseCert(certData); err != nil {
+			return err
+		}
+	} else {
+		clientset, err := common.InitClientSet()
+		if err != nil {
+			return err
+		}
+		if caCert, err = GetChaosdCACertFromCluster(context.Background(), o.chaosMeshNamespace, clientset.CtrlCli); err != nil {
+			return err
+		}
+	}
+
+	warnWeakCA(o.logger, caCert)
+	fmt.Print(CAForChaosdConfig(caCert))
+	return nil
+}
+
+// CAForChaosdConfig returns the CA cert as the PEM-encoded string which chaosd accepts as its trusted CA
+func CAForChaosdConfig(caCert *x509.Certificate) string {
+	return string(EncodeCertPEM(caCert))
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubectlscheme "k8s.io/kubectl/pkg/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCAForChaosdConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, _ := newTestCA(g, "test-ca")
+
+	value := CAForChaosdConfig(caCert)
+	g.Expect(value).To(HavePrefix("-----BEGIN CERTIFICATE-----"))
+
+	parsed, err := ParseCert([]byte(value))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(parsed.Equal(caCert)).To(BeTrue())
+}
+
+func TestGetChaosdCACertFromCluster(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	encryptedKey, err := EncodeEncryptedPrivateKeyPEM(caKey, []byte("passphrase"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	for _, data := range []map[string][]byte{
+		// the CA key is not required
+		{"ca.crt": EncodeCertPEM(caCert)},
+		// the CA key is not parsed
+		{"ca.crt": EncodeCertPEM(caCert), "ca.key": encryptedKey},
+	} {
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "chaos-testing", Name: "chaos-mesh-chaosd-client-certs"},
+			Data:       data,
+		}
+		c := fake.NewClientBuilder().WithScheme(kubectlscheme.Scheme).WithRuntimeObjects(secret).Build()
+
+		cert, err := GetChaosdCACertFromCluster(context.Background(), "chaos-testing", c)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cert.Equal(caCert)).To(BeTrue())
+
+		_, _, err = GetChaosdCAFileFromCluster(context.Background(), "chaos-testing", c)
+		g.Expect(err).To(HaveOccurred())
+	}
+
+	c := fake.NewClientBuilder().WithScheme(kubectlscheme.Scheme).WithRuntimeObjects(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "chaos-testing", Name: "chaos-mesh-chaosd-client-certs"},
+	}).Build()
+	_, err = GetChaosdCACertFromCluster(context.Background(), "chaos-testing", c)
+	g.Expect(err).To(HaveOccurred())
+	_, err = GetChaosdCACertFromCluster(context.Background(), "other", c)
+	g.Expect(err).To(HaveOccurred())
+}
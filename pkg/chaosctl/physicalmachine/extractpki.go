@@ -0,0 +1,75 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type PhysicalMachineExtractPKIOptions struct {
+	logger    logr.Logger
+	pkiPath   string
+	inputFile string
+}
+
+func NewPhysicalMachineExtractPKICmd(logger logr.Logger) (*cobra.Command, error) {
+	extractOption := &PhysicalMachineExtractPKIOptions{
+		logger: logger,
+	}
+
+	extractCmd := &cobra.Command{
+		Use:   `extract-pki`,
+		Short: `Extract the TLS certs of a physical machine from a tarball`,
+		Long: `Extract the TLS certs of a physical machine from a tar.gz bundle generated by package-pki, the file permissions are restored
+
+Examples:
+  # Extract the TLS certs in bundle.tar.gz into /etc/chaosd/pki
+  chaosctl pm extract-pki --in bundle.tar.gz --pki-dir /etc/chaosd/pki
+  `,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := extractOption.Validate(); err != nil {
+				return err
+			}
+			return extractOption.Run()
+		},
+	}
+	extractCmd.PersistentFlags().StringVar(&extractOption.pkiPath, "pki-dir", "/etc/chaosd/pki", "path to save the extracted TLS certs")
+	extractCmd.PersistentFlags().StringVar(&extractOption.inputFile, "in", "", "file path of the tar.gz bundle")
+	return extractCmd, nil
+}
+
+func (o *PhysicalMachineExtractPKIOptions) Validate() error {
+	if len(o.inputFile) == 0 {
+		return errors.New("--in must be specified")
+	}
+	return nil
+}
+
+func (o *PhysicalMachineExtractPKIOptions) Run() error {
+	f, err := os.Open(o.inputFile)
+	if err != nil {
+		return errors.Wrapf(err, "open bundle file %s failed", o.inputFile)
+	}
+	defer f.Close()
+
+	return ExtractPKI(f, o.pkiPath)
+}
@@ -30,6 +30,8 @@ type PhysicalMachineGenerateOptions struct {
 	outputPath string
 	caCertFile string
 	caKeyFile  string
+	dnsNames   []string
+	ips        []string
 }
 
 func NewPhysicalMachineGenerateCmd(logger logr.Logger) (*cobra.Command, error) {
@@ -53,6 +55,8 @@ func NewPhysicalMachineGenerateCmd(logger logr.Logger) (*cobra.Command, error) {
 	generateCmd.PersistentFlags().StringVar(&generateOption.outputPath, "path", "/etc/chaosd/pki", "path to save generated certs")
 	generateCmd.PersistentFlags().StringVar(&generateOption.caCertFile, "cacert", "", "file path to cacert file")
 	generateCmd.PersistentFlags().StringVar(&generateOption.caKeyFile, "cakey", "", "file path to cakey file")
+	generateCmd.PersistentFlags().StringSliceVar(&generateOption.dnsNames, "dns-names", nil, "additional DNS names of the generated cert (e.g. --dns-names host1.example.org,host1)")
+	generateCmd.PersistentFlags().StringSliceVar(&generateOption.ips, "ip-addresses", nil, "IP addresses of the generated cert (e.g. --ip-addresses 10.0.3.17)")
 	return generateCmd, nil
 }
 
@@ -71,8 +75,17 @@ func (o *PhysicalMachineGenerateOptions) Run() error {
 	if err != nil {
 		return err
 	}
+	warnWeakCA(o.logger, caCert)
 
-	serverCert, serverKey, err := NewCertAndKey(caCert, caKey)
+	ipAddresses, err := ParseIPAddresses(o.ips)
+	if err != nil {
+		return err
+	}
+
+	serverCert, serverKey, err := NewCertAndKey(caCert, caKey, CertOptions{
+		DNSNames:    o.dnsNames,
+		IPAddresses: ipAddresses,
+	})
 	if err != nil {
 		return err
 	}
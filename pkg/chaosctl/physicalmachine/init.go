@@ -20,6 +20,7 @@ import (
 	"crypto"
 	"crypto/x509"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -33,7 +34,6 @@ import (
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/util/keyutil"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -117,9 +117,16 @@ func (o *PhysicalMachineInitOptions) Run(args []string) error {
 	if err != nil {
 		return err
 	}
+	warnWeakCA(o.logger, caCert)
 
-	// generate chaosd cert and private key
-	serverCert, serverKey, err := NewCertAndKey(caCert, caKey)
+	// generate chaosd cert and private key, which is valid for the address of the remote physical machine
+	var certOptions CertOptions
+	if ip := net.ParseIP(o.remoteIP); ip != nil {
+		certOptions.IPAddresses = []net.IP{ip}
+	} else {
+		certOptions.DNSNames = []string{o.remoteIP}
+	}
+	serverCert, serverKey, err := NewCertAndKey(caCert, caKey, certOptions)
 	if err != nil {
 		return err
 	}
@@ -144,21 +151,18 @@ func (o *PhysicalMachineInitOptions) Run(args []string) error {
 }
 
 func GetChaosdCAFileFromCluster(ctx context.Context, namespace string, c client.Client) (caCert *x509.Certificate, caKey crypto.Signer, err error) {
-	var secret v1.Secret
-	if err := c.Get(ctx, types.NamespacedName{
-		Namespace: namespace,
-		Name:      "chaos-mesh-chaosd-client-certs",
-	}, &secret); err != nil {
-		return nil, nil, errors.Wrapf(err, "could not found secret `chaos-mesh-chaosd-client-certs` in namespace %s", namespace)
+	secret, err := getChaosdCASecret(ctx, namespace, c)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var caCertBytes []byte
-	var ok bool
-	if caCertBytes, ok = secret.Data["ca.crt"]; !ok {
-		return nil, nil, errors.New("could not found ca cert file in `chaos-mesh-chaosd-client-certs` secret")
+	caCertBytes, err := chaosdCACertFromSecret(secret)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var caKeyBytes []byte
+	var ok bool
 	if caKeyBytes, ok = secret.Data["ca.key"]; !ok {
 		return nil, nil, errors.New("could not found ca key file in `chaos-mesh-chaosd-client-certs` secret")
 	}
@@ -166,17 +170,50 @@ func GetChaosdCAFileFromCluster(ctx context.Context, namespace string, c client.
 	return ParseCertAndKey(caCertBytes, caKeyBytes)
 }
 
+// GetChaosdCACertFromCluster reads the CA cert only from the cluster, the CA key is neither required nor parsed
+func GetChaosdCACertFromCluster(ctx context.Context, namespace string, c client.Client) (*x509.Certificate, error) {
+	secret, err := getChaosdCASecret(ctx, namespace, c)
+	if err != nil {
+		return nil, err
+	}
+
+	caCertBytes, err := chaosdCACertFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCert(caCertBytes)
+}
+
+func getChaosdCASecret(ctx context.Context, namespace string, c client.Client) (*v1.Secret, error) {
+	var secret v1.Secret
+	if err := c.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      "chaos-mesh-chaosd-client-certs",
+	}, &secret); err != nil {
+		return nil, errors.Wrapf(err, "could not found secret `chaos-mesh-chaosd-client-certs` in namespace %s", namespace)
+	}
+	return &secret, nil
+}
+
+func chaosdCACertFromSecret(secret *v1.Secret) ([]byte, error) {
+	caCertBytes, ok := secret.Data["ca.crt"]
+	if !ok {
+		return nil, errors.New("could not found ca cert file in `chaos-mesh-chaosd-client-certs` secret")
+	}
+	return caCertBytes, nil
+}
+
 func writeCertAndKeyToRemote(sshTunnel *SshTunnel, pkiPath, pkiName string, cert *x509.Certificate, key crypto.Signer) error {
-	keyBytes, err := keyutil.MarshalPrivateKeyToPEM(key)
+	keyBytes, err := EncodePrivateKeyPEM(key)
 	if err != nil {
 		return err
 	}
 	if err := writeCertToRemote(sshTunnel, pkiPath, pkiName, cert); err != nil {
 		return err
 	}
-	return sshTunnel.SFTP(pathForKey(pkiPath, pkiName), keyBytes)
+	return sshTunnel.SFTP(pathForKey(pkiPath, pkiName), keyBytes, keyFileMode)
 }
 
 func writeCertToRemote(sshTunnel *SshTunnel, pkiPath, pkiName string, cert *x509.Certificate) error {
-	return sshTunnel.SFTP(pathForCert(pkiPath, pkiName), EncodeCertPEM(cert))
+	return sshTunnel.SFTP(pathForCert(pkiPath, pkiName), EncodeCertPEM(cert), certFileMode)
 }
@@ -0,0 +1,60 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+
+	"github.com/pkg/errors"
+)
+
+// KeyPolicy describes the private keys which are strong enough
+type KeyPolicy struct {
+	// MinRSABits is the minimum size of RSA keys
+	MinRSABits int
+	// AllowedCurves is the curves of ECDSA keys which are allowed, ECDSA keys are rejected if it's empty
+	AllowedCurves []elliptic.Curve
+	// AllowEd25519 represents whether Ed25519 keys are allowed
+	AllowEd25519 bool
+}
+
+// CheckKeyPolicy checks whether the private key satisfies the policy
+func CheckKeyPolicy(key crypto.Signer, policy KeyPolicy) error {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if bits := k.N.BitLen(); bits < policy.MinRSABits {
+			return errors.Errorf("RSA key size %d bits is less than the minimum %d bits", bits, policy.MinRSABits)
+		}
+	case *ecdsa.PrivateKey:
+		for _, curve := range policy.AllowedCurves {
+			if k.Curve == curve {
+				return nil
+			}
+		}
+		return errors.Errorf("ECDSA curve %s is not allowed", k.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		if !policy.AllowEd25519 {
+			return errors.New("Ed25519 keys are not allowed")
+		}
+	default:
+		return errors.Errorf("unsupported private key type %T", key)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCheckKeyPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	policy := KeyPolicy{
+		MinRSABits:    2048,
+		AllowedCurves: []elliptic.Curve{elliptic.P256()},
+		AllowEd25519:  false,
+	}
+
+	smallRSAKey, err := rsa.GenerateKey(cryptorand.Reader, 1024)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = CheckKeyPolicy(smallRSAKey, policy)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("1024"))
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), cryptorand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = CheckKeyPolicy(p384Key, policy)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("P-384"))
+
+	_, ed25519Key, err := ed25519.GenerateKey(cryptorand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(CheckKeyPolicy(ed25519Key, policy)).ToNot(Succeed())
+	policy.AllowEd25519 = true
+	g.Expect(CheckKeyPolicy(ed25519Key, policy)).To(Succeed())
+
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(CheckKeyPolicy(p256Key, policy)).To(Succeed())
+
+	rsaKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(CheckKeyPolicy(rsaKey, policy)).To(Succeed())
+}
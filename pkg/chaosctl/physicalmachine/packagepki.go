@@ -0,0 +1,191 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type PhysicalMachinePackagePKIOptions struct {
+	logger     logr.Logger
+	pkiPath    string
+	outputFile string
+}
+
+func NewPhysicalMachinePackagePKICmd(logger logr.Logger) (*cobra.Command, error) {
+	packageOption := &PhysicalMachinePackagePKIOptions{
+		logger: logger,
+	}
+
+	packageCmd := &cobra.Command{
+		Use:   `package-pki`,
+		Short: `Package the TLS certs of a physical machine into a tarball`,
+		Long: `Package the TLS certs of a physical machine (ca.crt, chaosd.crt and chaosd.key) into a tar.gz bundle, the file permissions are preserved
+
+Examples:
+  # Package the TLS certs in /etc/chaosd/pki into bundle.tar.gz
+  chaosctl pm package-pki --pki-dir /etc/chaosd/pki --out bundle.tar.gz
+  `,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := packageOption.Validate(); err != nil {
+				return err
+			}
+			return packageOption.Run()
+		},
+	}
+	packageCmd.PersistentFlags().StringVar(&packageOption.pkiPath, "pki-dir", "/etc/chaosd/pki", "path of the TLS certs to package")
+	packageCmd.PersistentFlags().StringVar(&packageOption.outputFile, "out", "", "file path of the generated tar.gz bundle")
+	return packageCmd, nil
+}
+
+func (o *PhysicalMachinePackagePKIOptions) Validate() error {
+	if len(o.outputFile) == 0 {
+		return errors.New("--out must be specified")
+	}
+	return nil
+}
+
+func (o *PhysicalMachinePackagePKIOptions) Run() error {
+	var bundle bytes.Buffer
+	if err := PackagePKI(o.pkiPath, &bundle); err != nil {
+		return err
+	}
+
+	// the bundle contains the private key, so keep it private even if the file exists
+	if err := writeFileAtomic(o.outputFile, bundle.Bytes(), keyFileMode); err != nil {
+		return errors.Wrapf(err, "write bundle file %s failed", o.outputFile)
+	}
+	return nil
+}
+
+// pkiFiles returns the names of the files in a PKI directory of a physical machine
+func pkiFiles() []string {
+	return []string{
+		filepath.Base(pathForCert("", "ca")),
+		filepath.Base(pathForCert("", ChaosdPkiName)),
+		filepath.Base(pathForKey("", ChaosdPkiName)),
+	}
+}
+
+// PackagePKI writes ca.crt, chaosd.crt and chaosd.key in pkiPath as a tar.gz bundle to w,
+// the file permissions are preserved
+func PackagePKI(pkiPath string, w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, name := range pkiFiles() {
+		filename := filepath.Join(pkiPath, name)
+		info, err := os.Stat(filename)
+		if err != nil {
+			return errors.Wrapf(err, "stat file %s failed", filename)
+		}
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return errors.Wrapf(err, "read file %s failed", filename)
+		}
+
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(info.Mode().Perm()),
+			Size:     int64(len(data)),
+			ModTime:  info.ModTime(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return errors.Wrapf(err, "write tar header of %s failed", name)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return errors.Wrapf(err, "write %s to tar failed", name)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "close tar writer failed")
+	}
+	return gw.Close()
+}
+
+// ExtractPKI extracts a tar.gz bundle written by PackagePKI from r into pkiPath,
+// the file permissions in the bundle are restored.
+// Nothing is written unless the bundle contains all of ca.crt, chaosd.crt and chaosd.key.
+func ExtractPKI(r io.Reader, pkiPath string) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.Wrap(err, "open gzip reader failed")
+	}
+	defer gr.Close()
+
+	allowed := make(map[string]bool)
+	for _, name := range pkiFiles() {
+		allowed[name] = true
+	}
+
+	type entry struct {
+		data []byte
+		mode os.FileMode
+	}
+	entries := make(map[string]entry)
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "read tar failed")
+		}
+		if header.Typeflag != tar.TypeReg || !allowed[header.Name] {
+			return errors.Errorf("unexpected entry %s in the bundle", header.Name)
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return errors.Wrapf(err, "read %s from tar failed", header.Name)
+		}
+		entries[header.Name] = entry{data: data, mode: os.FileMode(header.Mode).Perm()}
+	}
+
+	var missing []string
+	for _, name := range pkiFiles() {
+		if _, ok := entries[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		return errors.Errorf("missing %s in the bundle", strings.Join(missing, ", "))
+	}
+
+	for _, name := range pkiFiles() {
+		filename := filepath.Join(pkiPath, name)
+		if err := writeFileAtomic(filename, entries[name].data, entries[name].mode); err != nil {
+			return errors.Wrapf(err, "write file %s failed", filename)
+		}
+	}
+	return nil
+}
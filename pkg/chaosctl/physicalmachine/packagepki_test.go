@@ -0,0 +1,142 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPackageAndExtractPKI(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	cert, key, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	srcPath := t.TempDir()
+	g.Expect(WriteCertAndKey(srcPath, ChaosdPkiName, cert, key)).To(Succeed())
+	g.Expect(WriteCert(srcPath, "ca", caCert)).To(Succeed())
+	// non-default modes, which prove the modes come from the bundle rather than the writers
+	modes := map[string]os.FileMode{
+		pathForCert("", "ca"):          0640,
+		pathForCert("", ChaosdPkiName): 0444,
+		pathForKey("", ChaosdPkiName):  0400,
+	}
+	for name, mode := range modes {
+		g.Expect(os.Chmod(filepath.Join(srcPath, name), mode)).To(Succeed())
+	}
+
+	var bundle bytes.Buffer
+	g.Expect(PackagePKI(srcPath, &bundle)).To(Succeed())
+
+	dstPath := filepath.Join(t.TempDir(), "pki")
+	g.Expect(ExtractPKI(&bundle, dstPath)).To(Succeed())
+
+	for _, name := range pkiFiles() {
+		srcData, err := ioutil.ReadFile(filepath.Join(srcPath, name))
+		g.Expect(err).ToNot(HaveOccurred())
+		dstData, err := ioutil.ReadFile(filepath.Join(dstPath, name))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(dstData).To(Equal(srcData))
+	}
+
+	for name, mode := range modes {
+		info, err := os.Stat(filepath.Join(dstPath, name))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(info.Mode().Perm()).To(Equal(mode), name)
+	}
+}
+
+func TestPackagePKIOverwritesExistingFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	cert, key, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	srcPath := t.TempDir()
+	g.Expect(WriteCertAndKey(srcPath, ChaosdPkiName, cert, key)).To(Succeed())
+	g.Expect(WriteCert(srcPath, "ca", caCert)).To(Succeed())
+
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	g.Expect(ioutil.WriteFile(outputFile, []byte("stale"), 0644)).To(Succeed())
+	g.Expect(os.Chmod(outputFile, 0644)).To(Succeed())
+
+	o := &PhysicalMachinePackagePKIOptions{pkiPath: srcPath, outputFile: outputFile}
+	g.Expect(o.Run()).To(Succeed())
+
+	info, err := os.Stat(outputFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+	f, err := os.Open(outputFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+	g.Expect(ExtractPKI(f, filepath.Join(t.TempDir(), "pki"))).To(Succeed())
+
+	// a failed packaging leaves the existing bundle untouched
+	g.Expect(os.Remove(pathForKey(srcPath, ChaosdPkiName))).To(Succeed())
+	g.Expect(o.Run()).ToNot(Succeed())
+	f2, err := os.Open(outputFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer f2.Close()
+	g.Expect(ExtractPKI(f2, filepath.Join(t.TempDir(), "pki"))).To(Succeed())
+}
+
+func TestExtractPKIRejectsUnexpectedEntry(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var bundle bytes.Buffer
+	gw := gzip.NewWriter(&bundle)
+	tw := tar.NewWriter(gw)
+	g.Expect(tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../evil", Mode: 0644})).To(Succeed())
+	g.Expect(tw.Close()).To(Succeed())
+	g.Expect(gw.Close()).To(Succeed())
+
+	err := ExtractPKI(&bundle, t.TempDir())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("unexpected entry"))
+}
+
+func TestExtractPKIRejectsIncompleteBundle(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var bundle bytes.Buffer
+	gw := gzip.NewWriter(&bundle)
+	tw := tar.NewWriter(gw)
+	name := filepath.Base(pathForCert("", ChaosdPkiName))
+	g.Expect(tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644})).To(Succeed())
+	g.Expect(tw.Close()).To(Succeed())
+	g.Expect(gw.Close()).To(Succeed())
+
+	dstPath := filepath.Join(t.TempDir(), "pki")
+	err := ExtractPKI(&bundle, dstPath)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("ca.crt"))
+	g.Expect(err.Error()).To(ContainSubstring("chaosd.key"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("chaosd.crt"))
+
+	_, err = os.Stat(dstPath)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
@@ -16,8 +16,10 @@
 package physicalmachine
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	cryptorand "crypto/rand"
 	"crypto/rsa"
@@ -25,25 +27,80 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"math/big"
+	"net"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/client-go/util/keyutil"
 )
 
 const (
+	// rsaKeySize is the default size of RSA keys, which is also the minimum size allowed
 	rsaKeySize    = 2048
 	ChaosdPkiName = "chaosd"
+	// certFileMode is the permissions of the written certificates, which are public
+	certFileMode = os.FileMode(0644)
+	// keyFileMode is the permissions of the written private keys, which are readable by the owner only
+	keyFileMode = os.FileMode(0600)
 	// CertificateBlockType is a possible value for pem.Block.Type.
 	CertificateBlockType = "CERTIFICATE"
 	// CertificateValidity defines the validity for all the signed certificates generated by kubeadm
 	CertificateValidity = time.Hour * 24 * 1825
+	// CAValidity defines the default validity of the certificate authorities created by NewCertificateAuthority
+	CAValidity = time.Hour * 24 * 3650
+	// ChaosdCommonName is the default CommonName of the signed certificates
+	ChaosdCommonName = "chaosd.chaos-mesh.org"
+	// ChaosdCACommonName is the CommonName of the certificate authorities created by NewCertificateAuthority
+	ChaosdCACommonName = "chaosd-ca"
+	// DefaultClusterDomain is the default DNS domain of a Kubernetes cluster
+	DefaultClusterDomain = "cluster.local"
 )
 
+var (
+	defaultKeyTypeLock sync.RWMutex
+	defaultKeyType     = x509.RSA
+)
+
+// CertOptions is the options to create a signed certificate, the zero value represents the default options
+type CertOptions struct {
+	// Subject is the subject of the certificate, ChaosdCommonName is used if its CommonName is empty
+	Subject pkix.Name
+	// Validity is the validity duration of the certificate, CertificateValidity is used if it's zero
+	Validity time.Duration
+	// DNSNames is the DNS names added to the default DNS names of the certificate
+	DNSNames []string
+	// IPAddresses is the IP addresses of the certificate
+	IPAddresses []net.IP
+	// NoDefaultSANs represents whether to leave the default DNS names out of the certificate
+	NoDefaultSANs bool
+	// Key is the options to create the private key, it's used by NewCertAndKey only
+	Key KeyOptions
+}
+
+// KeyOptions is the options to create a private key, the zero value represents the default options
+type KeyOptions struct {
+	// RSAKeySize is the size of RSA keys in bits, 2048 is used if it's zero. It's ignored by other key types.
+	RSAKeySize int
+	// Curve is the curve of ECDSA keys, which is one of P-256, P-384 and P-521, P-256 is used if it's nil.
+	// It's ignored by other key types.
+	Curve elliptic.Curve
+}
+
+// defaultDNSNames is the DNS names included in the signed certificates unless CertOptions.NoDefaultSANs is set
+var defaultDNSNames = []string{"chaosd.chaos-mesh.org", "localhost"}
+
+// utf8BOM is the byte order mark prepended to text files by some Windows editors
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
 func ParseCertAndKey(certData, keyData []byte) (*x509.Certificate, crypto.Signer, error) {
 	caCert, err := ParseCert(certData)
 	if err != nil {
@@ -57,41 +114,119 @@ func ParseCertAndKey(certData, keyData []byte) (*x509.Certificate, crypto.Signer
 	return caCert, caKey, nil
 }
 
+// ParsePrivateKey parses the unencrypted private key in data, ErrKeyEncrypted is returned if the key is encrypted,
+// use ParseEncryptedPrivateKey to parse it with the passphrase
 func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	if block := findPrivateKeyBlock(data); block != nil && isEncryptedBlock(block) {
+		return nil, ErrKeyEncrypted
+	}
+
 	privKey, err := keyutil.ParsePrivateKeyPEM(data)
 	if err != nil {
 		return nil, fmt.Errorf("error reading private key file: %v", err)
 	}
+	return toSigner(privKey)
+}
 
+func toSigner(privKey interface{}) (crypto.Signer, error) {
 	var key crypto.Signer
-	// Allow RSA and ECDSA formats only
+	// Allow RSA, ECDSA and Ed25519 formats only
 	switch k := privKey.(type) {
 	case *rsa.PrivateKey:
 		key = k
 	case *ecdsa.PrivateKey:
 		key = k
+	case ed25519.PrivateKey:
+		key = k
 	default:
-		return nil, errors.New("the private key file is neither in RSA nor ECDSA format")
+		return nil, errors.New("the private key file is neither in RSA, ECDSA nor Ed25519 format")
 	}
 	return key, nil
 }
 
+// ParseCert parses the PEM encoded certificates in data and returns the first one only,
+// use ParseCerts to get all of them
 func ParseCert(data []byte) (*x509.Certificate, error) {
-	caCerts, err := certutil.ParseCertsPEM(data)
+	certs, err := ParseCerts(data)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// ParseCerts parses all the PEM encoded certificates in data in order, it returns an error
+// if there is no certificate
+func ParseCerts(data []byte) ([]*x509.Certificate, error) {
+	certs, err := certutil.ParseCertsPEM(sanitizePEM(data))
 	if err != nil {
 		return nil, errors.Wrap(err, "parse certs pem failed")
 	}
-	return caCerts[0], nil
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found in pem data")
+	}
+	return certs, nil
+}
+
+// LoadCertFromPath loads the certificate named name in pkiPath, which is written by WriteCert
+func LoadCertFromPath(pkiPath, name string) (*x509.Certificate, error) {
+	certificatePath := pathForCert(pkiPath, name)
+	data, err := ioutil.ReadFile(certificatePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read cert file %s", certificatePath)
+	}
+	return ParseCert(data)
+}
+
+// CertTimeRemaining returns the duration until the certificate expires, it's negative if the certificate has expired
+func CertTimeRemaining(cert *x509.Certificate) time.Duration {
+	return time.Until(cert.NotAfter)
+}
+
+// CertExpiresWithin reports whether the certificate expires within d (or has already expired),
+// e.g. CertExpiresWithin(cert, 30*24*time.Hour) means the certificate should be renewed
+func CertExpiresWithin(cert *x509.Certificate, d time.Duration) bool {
+	return CertTimeRemaining(cert) < d
+}
+
+// sanitizePEM strips a leading UTF-8 BOM and normalizes CRLF and CR line endings to LF,
+// so that PEM files edited on Windows could be parsed
+func sanitizePEM(data []byte) []byte {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+}
+
+// WarnWeakSignature returns a warning if the certificate is signed with SHA-1 (or an even weaker hash),
+// which is rejected by modern clients, otherwise it returns an empty string
+func WarnWeakSignature(cert *x509.Certificate) string {
+	switch cert.SignatureAlgorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return fmt.Sprintf("WARNING: certificate %q is signed with weak signature algorithm %s, modern clients will reject it",
+			cert.Subject.CommonName, cert.SignatureAlgorithm)
+	}
+	return ""
+}
+
+// warnWeakCA logs the warning from WarnWeakSignature, if any, for the loaded CA certificate
+func warnWeakCA(logger logr.Logger, caCert *x509.Certificate) {
+	if warning := WarnWeakSignature(caCert); len(warning) != 0 {
+		logger.Info(warning)
+	}
 }
 
 // NewCertAndKey creates new certificate and key by passing the certificate authority certificate and key
-func NewCertAndKey(caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
-	key, err := NewPrivateKey(x509.RSA)
+func NewCertAndKey(caCert *x509.Certificate, caKey crypto.Signer, opts CertOptions) (*x509.Certificate, crypto.Signer, error) {
+	// check the CA before generating the key, which could be slow
+	if err := checkSigningCA(caCert, caKey); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid certificate authority")
+	}
+
+	key, err := NewPrivateKey(x509.UnknownPublicKeyAlgorithm, opts.Key)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "unable to create private key")
 	}
 
-	cert, err := NewSignedCert(key, caCert, caKey, false)
+	cert, err := NewSignedCert(key, caCert, caKey, false, opts)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "unable to sign certificate")
 	}
@@ -99,35 +234,141 @@ func NewCertAndKey(caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certifi
 	return cert, key, nil
 }
 
-func NewPrivateKey(keyType x509.PublicKeyAlgorithm) (crypto.Signer, error) {
-	if keyType == x509.ECDSA {
-		return ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+// GenerateSignedCertPEM creates new certificate and key like NewCertAndKey, and returns them PEM-encoded
+// without writing any files
+func GenerateSignedCertPEM(caCert *x509.Certificate, caKey crypto.Signer, opts CertOptions) (certPEM, keyPEM []byte, err error) {
+	cert, key, err := NewCertAndKey(caCert, caKey, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err = EncodePrivateKeyPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return EncodeCertPEM(cert), keyPEM, nil
+}
+
+// SetDefaultKeyType sets the key type used by NewPrivateKey when no explicit type is passed, it's RSA by default.
+// Only RSA, ECDSA and Ed25519 are accepted.
+func SetDefaultKeyType(keyType x509.PublicKeyAlgorithm) error {
+	if err := checkKeyType(keyType); err != nil {
+		return err
+	}
+
+	defaultKeyTypeLock.Lock()
+	defer defaultKeyTypeLock.Unlock()
+	defaultKeyType = keyType
+	return nil
+}
+
+// checkKeyType checks that NewPrivateKey is able to create keys of the type
+func checkKeyType(keyType x509.PublicKeyAlgorithm) error {
+	switch keyType {
+	case x509.RSA, x509.ECDSA, x509.Ed25519:
+		return nil
+	}
+	return errors.Errorf("unsupported key type %s", keyType)
+}
+
+// DefaultKeyType returns the key type set by SetDefaultKeyType
+func DefaultKeyType() x509.PublicKeyAlgorithm {
+	defaultKeyTypeLock.RLock()
+	defer defaultKeyTypeLock.RUnlock()
+	return defaultKeyType
+}
+
+// NewPrivateKey creates a private key of the given type,
+// the default key type is used if keyType is x509.UnknownPublicKeyAlgorithm
+func NewPrivateKey(keyType x509.PublicKeyAlgorithm, opts KeyOptions) (crypto.Signer, error) {
+	if keyType == x509.UnknownPublicKeyAlgorithm {
+		keyType = DefaultKeyType()
 	}
 
-	return rsa.GenerateKey(cryptorand.Reader, rsaKeySize)
+	switch keyType {
+	case x509.ECDSA:
+		curve := opts.Curve
+		if curve == nil {
+			curve = elliptic.P256()
+		}
+		switch curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return nil, errors.Errorf("unsupported ECDSA curve %s", curve.Params().Name)
+		}
+		return ecdsa.GenerateKey(curve, cryptorand.Reader)
+	case x509.Ed25519:
+		_, key, err := ed25519.GenerateKey(cryptorand.Reader)
+		return key, err
+	case x509.RSA:
+		size := opts.RSAKeySize
+		if size == 0 {
+			size = rsaKeySize
+		}
+		if size < rsaKeySize {
+			return nil, errors.Errorf("RSA key size %d bits is less than the minimum %d bits", size, rsaKeySize)
+		}
+		if size%8 != 0 {
+			return nil, errors.Errorf("RSA key size %d bits is not a multiple of 8", size)
+		}
+		return rsa.GenerateKey(cryptorand.Reader, size)
+	default:
+		return nil, checkKeyType(keyType)
+	}
 }
 
-// NewSignedCert creates a signed certificate using the given CA certificate and key
-func NewSignedCert(key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer, isCA bool) (*x509.Certificate, error) {
-	serial, err := cryptorand.Int(cryptorand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+// NewSignedCert creates a signed certificate using the given CA certificate and key,
+// the CA certificate must be a CA allowed to sign certificates and match the CA key
+func NewSignedCert(key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer, isCA bool, opts CertOptions) (*x509.Certificate, error) {
+	if err := checkSigningCA(caCert, caKey); err != nil {
+		return nil, errors.Wrap(err, "invalid certificate authority")
+	}
+
+	validity, err := validityOrDefault(opts.Validity, CertificateValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	var dnsNames []string
+	if !opts.NoDefaultSANs {
+		dnsNames = append(dnsNames, defaultDNSNames...)
+	}
+	dnsNames = dedupStrings(append(dnsNames, opts.DNSNames...))
+
+	ipAddresses, err := dedupIPs(opts.IPAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := newSerialNumber()
 	if err != nil {
 		return nil, err
 	}
 
-	keyUsage := x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
+	keyUsage := x509.KeyUsageDigitalSignature
+	// only RSA keys could be used for key encipherment
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
 	if isCA {
 		keyUsage |= x509.KeyUsageCertSign
 	}
 
-	notAfter := time.Now().Add(CertificateValidity).UTC()
+	// the default DNS names are not derived from the subject, so changing the subject keeps the SANs
+	subject := opts.Subject
+	if len(subject.CommonName) == 0 {
+		subject.CommonName = ChaosdCommonName
+	}
+
+	notBefore := time.Now().UTC()
+	notAfter := notBefore.Add(validity)
 
 	certTmpl := x509.Certificate{
-		Subject: pkix.Name{
-			CommonName: "chaosd.chaos-mesh.org",
-		},
-		DNSNames:              []string{"chaosd.chaos-mesh.org", "localhost"},
+		Subject:               subject,
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
 		SerialNumber:          serial,
-		NotBefore:             caCert.NotBefore,
+		NotBefore:             notBefore,
 		NotAfter:              notAfter,
 		KeyUsage:              keyUsage,
 		BasicConstraintsValid: true,
@@ -140,6 +381,157 @@ func NewSignedCert(key crypto.Signer, caCert *x509.Certificate, caKey crypto.Sig
 	return x509.ParseCertificate(certDERBytes)
 }
 
+// NewCertificateAuthority creates a self-signed certificate authority with a new key of the given type,
+// CAValidity is used if validity is zero
+func NewCertificateAuthority(keyType x509.PublicKeyAlgorithm, keyOpts KeyOptions, validity time.Duration) (*x509.Certificate, crypto.Signer, error) {
+	validity, err := validityOrDefault(validity, CAValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := NewPrivateKey(keyType, keyOpts)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to create private key")
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notBefore := time.Now().UTC()
+	certTmpl := x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: ChaosdCACommonName,
+		},
+		SerialNumber:          serial,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDERBytes, err := x509.CreateCertificate(cryptorand.Reader, &certTmpl, &certTmpl, key.Public(), key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to self-sign certificate authority")
+	}
+	cert, err := x509.ParseCertificate(certDERBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// newSerialNumber returns a random serial number for a new certificate
+func newSerialNumber() (*big.Int, error) {
+	return cryptorand.Int(cryptorand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+}
+
+// validityOrDefault returns defaultValidity if validity is zero, a negative validity is an error
+func validityOrDefault(validity, defaultValidity time.Duration) (time.Duration, error) {
+	if validity < 0 {
+		return 0, errors.Errorf("certificate validity %s cannot be negative", validity)
+	}
+	if validity == 0 {
+		return defaultValidity, nil
+	}
+	return validity, nil
+}
+
+// ParseIPAddresses parses the IP addresses for CertOptions.IPAddresses, an invalid IP address is an error
+func ParseIPAddresses(ips []string) ([]net.IP, error) {
+	parsed := make([]net.IP, 0, len(ips))
+	for _, s := range ips {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.Errorf("invalid ip address %q", s)
+		}
+		parsed = append(parsed, ip)
+	}
+	return parsed, nil
+}
+
+// dedupStrings removes the duplicated strings, keeping the order of the first occurrences
+func dedupStrings(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	return result
+}
+
+// dedupIPs removes the duplicated IP addresses, keeping the order of the first occurrences,
+// an IP address which is neither IPv4 nor IPv6 is an error
+func dedupIPs(ips []net.IP) ([]net.IP, error) {
+	seen := make(map[string]bool, len(ips))
+	var result []net.IP
+	for _, ip := range ips {
+		if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+			return nil, errors.Errorf("invalid ip address %q", ip.String())
+		}
+		key := ip.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, ip)
+	}
+	return result, nil
+}
+
+// ClusterServiceSANs returns the DNS names which resolve to the service inside a Kubernetes cluster,
+// DefaultClusterDomain is used if clusterDomain is empty
+func ClusterServiceSANs(service, namespace, clusterDomain string) []string {
+	if len(clusterDomain) == 0 {
+		clusterDomain = DefaultClusterDomain
+	}
+	return []string{
+		service,
+		fmt.Sprintf("%s.%s", service, namespace),
+		fmt.Sprintf("%s.%s.svc", service, namespace),
+		fmt.Sprintf("%s.%s.svc.%s", service, namespace, clusterDomain),
+	}
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it to filename,
+// so that a partial file never lands on disk. The permissions of the file are exactly perm, regardless of the umask.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp-")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	// the temporary file is left only if the rename fails
+	defer os.Remove(tmpName)
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, filename)
+}
+
 // WriteCertAndKey stores certificate and key at the specified location
 func WriteCertAndKey(pkiPath string, name string, cert *x509.Certificate, key crypto.Signer) error {
 	if err := WriteKey(pkiPath, name, key); err != nil {
@@ -151,12 +543,24 @@ func WriteCertAndKey(pkiPath string, name string, cert *x509.Certificate, key cr
 
 // WriteCert stores the given certificate at the given location
 func WriteCert(pkiPath, name string, cert *x509.Certificate) error {
+	return WriteCertWithHeader(pkiPath, name, cert, "")
+}
+
+// WriteCertWithHeader stores the given certificate at the given location, with the header comment
+// (e.g. "Issued by Chaos Mesh on ...") prepended above the PEM block.
+// Each line of the comment is prefixed with "# ", PEM parsers ignore the text before the block.
+func WriteCertWithHeader(pkiPath, name string, cert *x509.Certificate, headerComment string) error {
 	if cert == nil {
 		return errors.New("certificate cannot be nil when writing to file")
 	}
 
+	data := EncodeCertPEM(cert)
+	if len(headerComment) != 0 {
+		data = append(encodeHeaderComment(headerComment), data...)
+	}
+
 	certificatePath := pathForCert(pkiPath, name)
-	if err := certutil.WriteCert(certificatePath, EncodeCertPEM(cert)); err != nil {
+	if err := writeFileAtomic(certificatePath, data, certFileMode); err != nil {
 		return errors.Wrapf(err, "unable to write certificate to file %s", certificatePath)
 	}
 
@@ -170,11 +574,11 @@ func WriteKey(pkiPath, name string, key crypto.Signer) error {
 	}
 
 	privateKeyPath := pathForKey(pkiPath, name)
-	encoded, err := keyutil.MarshalPrivateKeyToPEM(key)
+	encoded, err := EncodePrivateKeyPEM(key)
 	if err != nil {
-		return errors.Wrapf(err, "unable to marshal private key to PEM")
+		return err
 	}
-	if err := keyutil.WriteKey(privateKeyPath, encoded); err != nil {
+	if err := writeFileAtomic(privateKeyPath, encoded, keyFileMode); err != nil {
 		return errors.Wrapf(err, "unable to write private key to file %s", privateKeyPath)
 	}
 
@@ -190,6 +594,53 @@ func EncodeCertPEM(cert *x509.Certificate) []byte {
 	return pem.EncodeToMemory(&block)
 }
 
+// EncodePrivateKeyPEM returns PEM-encoded private key data,
+// Ed25519 keys are encoded in PKCS#8 as keyutil doesn't support them
+func EncodePrivateKeyPEM(key crypto.Signer) ([]byte, error) {
+	if k, ok := key.(ed25519.PrivateKey); ok {
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to marshal private key to PEM")
+		}
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  keyutil.PrivateKeyBlockType,
+			Bytes: der,
+		}), nil
+	}
+
+	encoded, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to marshal private key to PEM")
+	}
+	return encoded, nil
+}
+
+// ConcatPEM joins the PEM blocks, making sure each of them ends with a newline
+func ConcatPEM(blocks ...[]byte) []byte {
+	var buf bytes.Buffer
+	for _, block := range blocks {
+		if len(block) == 0 {
+			continue
+		}
+		buf.Write(block)
+		if block[len(block)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// encodeHeaderComment turns the comment into "# " prefixed lines
+func encodeHeaderComment(comment string) []byte {
+	var buf bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(comment, "\n"), "\n") {
+		buf.WriteString("# ")
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
 func pathForCert(pkiPath, name string) string {
 	return filepath.Join(pkiPath, fmt.Sprintf("%s.crt", name))
 }
@@ -0,0 +1,613 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestCA creates a self-signed CA certificate and key for tests
+func newTestCA(g *WithT, commonName string) (*x509.Certificate, crypto.Signer) {
+	return newTestCert(g, commonName, true, nil, nil)
+}
+
+// newTestCert creates a certificate and key signed by the given parent for tests,
+// the certificate is self-signed if parent is nil
+func newTestCert(g *WithT, commonName string, isCA bool, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	key, err := NewPrivateKey(x509.ECDSA, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	serial, err := cryptorand.Int(cryptorand.Reader, big.NewInt(math.MaxInt64))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if isCA {
+		keyUsage |= x509.KeyUsageCertSign
+	}
+	tmpl := x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: commonName,
+		},
+		SerialNumber:          serial,
+		NotBefore:             time.Now().Add(-time.Hour).UTC(),
+		NotAfter:              time.Now().Add(24 * time.Hour).UTC(),
+		KeyUsage:              keyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if parent == nil {
+		parent, parentKey = &tmpl, key
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, &tmpl, parent, key.Public(), parentKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	g.Expect(err).ToNot(HaveOccurred())
+	return cert, key
+}
+
+func TestParseCert(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, _ := newTestCA(g, "test-ca")
+	certPEM := EncodeCertPEM(caCert)
+
+	cert, err := ParseCert(certPEM)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Equal(caCert)).To(BeTrue())
+
+	withBOM := append(append([]byte{}, utf8BOM...), certPEM...)
+	cert, err = ParseCert(withBOM)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Equal(caCert)).To(BeTrue())
+
+	withCRLF := bytes.ReplaceAll(withBOM, []byte("\n"), []byte("\r\n"))
+	cert, err = ParseCert(withCRLF)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Equal(caCert)).To(BeTrue())
+
+	_, err = ParseCert(append(append([]byte{}, utf8BOM...), []byte("not a pem file")...))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestParseCerts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	intermediate, intermediateKey := newTestCert(g, "test-intermediate", true, caCert, caKey)
+	leaf, _ := newTestCert(g, "test-leaf", false, intermediate, intermediateKey)
+	bundle := ConcatPEM(EncodeCertPEM(leaf), EncodeCertPEM(intermediate), EncodeCertPEM(caCert))
+
+	certs, err := ParseCerts(bundle)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(certs).To(HaveLen(3))
+	g.Expect(certs[0].Equal(leaf)).To(BeTrue())
+	g.Expect(certs[1].Equal(intermediate)).To(BeTrue())
+	g.Expect(certs[2].Equal(caCert)).To(BeTrue())
+
+	// ParseCert returns the leaf only
+	cert, err := ParseCert(bundle)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Equal(leaf)).To(BeTrue())
+
+	for _, data := range [][]byte{nil, []byte(""), utf8BOM} {
+		_, err = ParseCerts(data)
+		g.Expect(err).To(HaveOccurred())
+		_, err = ParseCert(data)
+		g.Expect(err).To(HaveOccurred())
+	}
+}
+
+func TestWarnWeakSignature(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	weak := newTestRSACA(g, "legacy-ca", x509.SHA1WithRSA)
+	g.Expect(weak.SignatureAlgorithm).To(Equal(x509.SHA1WithRSA))
+	g.Expect(WarnWeakSignature(weak)).To(ContainSubstring("legacy-ca"))
+	g.Expect(WarnWeakSignature(weak)).To(ContainSubstring("SHA1-RSA"))
+
+	strong := newTestRSACA(g, "modern-ca", x509.SHA256WithRSA)
+	g.Expect(WarnWeakSignature(strong)).To(BeEmpty())
+
+	caCert, _ := newTestCA(g, "test-ca")
+	g.Expect(WarnWeakSignature(caCert)).To(BeEmpty())
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zapr.NewLogger(zap.New(core))
+	warnWeakCA(logger, weak)
+	g.Expect(logs.Len()).To(Equal(1))
+	g.Expect(logs.All()[0].Message).To(ContainSubstring("legacy-ca"))
+	warnWeakCA(logger, strong)
+	g.Expect(logs.Len()).To(Equal(1))
+}
+
+// newTestRSACA creates a self-signed RSA CA certificate signed with the given algorithm,
+// and parses it back from PEM like a CA loaded from disk
+func newTestRSACA(g *WithT, commonName string, sigAlgo x509.SignatureAlgorithm) *x509.Certificate {
+	key, err := NewPrivateKey(x509.RSA, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	tmpl := x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour).UTC(),
+		NotAfter:              time.Now().Add(24 * time.Hour).UTC(),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SignatureAlgorithm:    sigAlgo,
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, &tmpl, &tmpl, key.Public(), key)
+	g.Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	cert, err = ParseCert(EncodeCertPEM(cert))
+	g.Expect(err).ToNot(HaveOccurred())
+	return cert
+}
+
+func TestWriteCertWithHeader(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, _ := newTestCA(g, "test-ca")
+	pkiPath := t.TempDir()
+
+	header := "Issued by Chaos Mesh on 2021-12-01\nIssuer: test-ca"
+	g.Expect(WriteCertWithHeader(pkiPath, "ca", caCert, header)).To(Succeed())
+
+	data, err := ioutil.ReadFile(pathForCert(pkiPath, "ca"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(HavePrefix("# Issued by Chaos Mesh on 2021-12-01\n# Issuer: test-ca\n-----BEGIN CERTIFICATE-----"))
+
+	cert, err := ParseCert(data)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Equal(caCert)).To(BeTrue())
+}
+
+func TestSetDefaultKeyType(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+
+	cert, _, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.PublicKeyAlgorithm).To(Equal(x509.RSA))
+
+	g.Expect(SetDefaultKeyType(x509.ECDSA)).To(Succeed())
+	defer SetDefaultKeyType(x509.RSA)
+	g.Expect(DefaultKeyType()).To(Equal(x509.ECDSA))
+
+	cert, key, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.PublicKeyAlgorithm).To(Equal(x509.ECDSA))
+	g.Expect(key).To(BeAssignableToTypeOf(&ecdsa.PrivateKey{}))
+
+	// unsupported key types are rejected instead of falling back to RSA
+	err = SetDefaultKeyType(x509.DSA)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("unsupported key type DSA"))
+	g.Expect(DefaultKeyType()).To(Equal(x509.ECDSA))
+
+	_, err = NewPrivateKey(x509.DSA, KeyOptions{})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("unsupported key type DSA"))
+	_, err = NewPrivateKey(x509.PublicKeyAlgorithm(42), KeyOptions{})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestClusterServiceSANs(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(ClusterServiceSANs("chaosd", "chaos-testing", "cluster.local")).To(Equal([]string{
+		"chaosd",
+		"chaosd.chaos-testing",
+		"chaosd.chaos-testing.svc",
+		"chaosd.chaos-testing.svc.cluster.local",
+	}))
+
+	g.Expect(ClusterServiceSANs("chaosd", "chaos-testing", "example.org")).To(ContainElement("chaosd.chaos-testing.svc.example.org"))
+	g.Expect(ClusterServiceSANs("chaosd", "chaos-testing", "")).To(ContainElement("chaosd.chaos-testing.svc.cluster.local"))
+}
+
+func TestConcatPEM(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	keyPEM, err := EncodePrivateKeyPEM(caKey)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// drop the trailing newline to check it's added back
+	certPEM := bytes.TrimRight(EncodeCertPEM(caCert), "\n")
+	data := ConcatPEM(certPEM, nil, keyPEM)
+	g.Expect(data).To(HaveSuffix("\n"))
+
+	block, rest := pem.Decode(data)
+	g.Expect(block).ToNot(BeNil())
+	g.Expect(block.Type).To(Equal(CertificateBlockType))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Equal(caCert)).To(BeTrue())
+
+	key, err := ParsePrivateKey(rest)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.Public()).To(Equal(caKey.Public()))
+}
+
+func TestNewSignedCertValidity(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	key, err := NewPrivateKey(x509.ECDSA, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	before := time.Now().Add(-time.Second)
+
+	cert, err := NewSignedCert(key, caCert, caKey, false, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.NotBefore).To(BeTemporally(">=", before.Truncate(time.Second)))
+	g.Expect(cert.NotBefore).ToNot(Equal(caCert.NotBefore))
+	g.Expect(cert.NotAfter.Sub(cert.NotBefore)).To(Equal(CertificateValidity))
+
+	cert, err = NewSignedCert(key, caCert, caKey, false, CertOptions{Validity: 90 * 24 * time.Hour})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.NotAfter.Sub(cert.NotBefore)).To(Equal(90 * 24 * time.Hour))
+
+	_, err = NewSignedCert(key, caCert, caKey, false, CertOptions{Validity: -time.Hour})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("negative"))
+
+	cert, _, err = NewCertAndKey(caCert, caKey, CertOptions{Validity: 30 * 24 * time.Hour})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.NotAfter.Sub(cert.NotBefore)).To(Equal(30 * 24 * time.Hour))
+}
+
+func TestNewSignedCertSubject(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+
+	cert, _, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Subject.CommonName).To(Equal(ChaosdCommonName))
+	g.Expect(cert.Subject.Organization).To(BeEmpty())
+
+	cert, _, err = NewCertAndKey(caCert, caKey, CertOptions{
+		Subject: pkix.Name{
+			CommonName:         "host-1.example.org",
+			Organization:       []string{"Chaos Mesh"},
+			OrganizationalUnit: []string{"chaosd"},
+		},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Subject.CommonName).To(Equal("host-1.example.org"))
+	g.Expect(cert.Subject.Organization).To(Equal([]string{"Chaos Mesh"}))
+	g.Expect(cert.Subject.OrganizationalUnit).To(Equal([]string{"chaosd"}))
+	// the default SANs are kept regardless of the subject
+	g.Expect(cert.DNSNames).To(Equal([]string{"chaosd.chaos-mesh.org", "localhost"}))
+
+	cert, _, err = NewCertAndKey(caCert, caKey, CertOptions{
+		Subject: pkix.Name{Organization: []string{"Chaos Mesh"}},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Subject.CommonName).To(Equal(ChaosdCommonName))
+	g.Expect(cert.Subject.Organization).To(Equal([]string{"Chaos Mesh"}))
+}
+
+func TestNewSignedCertSANs(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+
+	cert, _, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.DNSNames).To(Equal([]string{"chaosd.chaos-mesh.org", "localhost"}))
+	g.Expect(cert.IPAddresses).To(BeEmpty())
+
+	ips, err := ParseIPAddresses([]string{"10.0.3.17", "fd00::17", "10.0.3.17"})
+	g.Expect(err).ToNot(HaveOccurred())
+	cert, _, err = NewCertAndKey(caCert, caKey, CertOptions{
+		DNSNames:    []string{"host-1.example.org", "localhost", "host-1.example.org"},
+		IPAddresses: ips,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.DNSNames).To(Equal([]string{"chaosd.chaos-mesh.org", "localhost", "host-1.example.org"}))
+	g.Expect(cert.IPAddresses).To(HaveLen(2))
+	g.Expect(cert.IPAddresses[0].Equal(net.ParseIP("10.0.3.17"))).To(BeTrue())
+	g.Expect(cert.IPAddresses[1].Equal(net.ParseIP("fd00::17"))).To(BeTrue())
+	g.Expect(cert.VerifyHostname("10.0.3.17")).To(Succeed())
+
+	cert, _, err = NewCertAndKey(caCert, caKey, CertOptions{
+		DNSNames:      []string{"host-1.example.org"},
+		NoDefaultSANs: true,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.DNSNames).To(Equal([]string{"host-1.example.org"}))
+
+	_, err = ParseIPAddresses([]string{"10.0.3.17", "10.0.3.256"})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("10.0.3.256"))
+
+	_, _, err = NewCertAndKey(caCert, caKey, CertOptions{IPAddresses: []net.IP{{10, 0, 3}}})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestNewCertificateAuthority(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey, err := NewCertificateAuthority(x509.ECDSA, KeyOptions{}, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(caCert.IsCA).To(BeTrue())
+	g.Expect(caCert.KeyUsage & x509.KeyUsageCertSign).ToNot(BeZero())
+	g.Expect(caCert.Subject.CommonName).To(Equal(ChaosdCACommonName))
+	g.Expect(caCert.PublicKeyAlgorithm).To(Equal(x509.ECDSA))
+	g.Expect(caCert.NotAfter.Sub(caCert.NotBefore)).To(Equal(CAValidity))
+	g.Expect(CAValidity > CertificateValidity).To(BeTrue())
+	g.Expect(IsSelfSigned(caCert)).To(BeTrue())
+
+	cert, _, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	matched, err := VerifyAgainstAny(cert, caCert)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(matched).To(Equal(caCert))
+
+	caCert, _, err = NewCertificateAuthority(x509.RSA, KeyOptions{}, 365*24*time.Hour)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(caCert.PublicKeyAlgorithm).To(Equal(x509.RSA))
+	g.Expect(caCert.NotAfter.Sub(caCert.NotBefore)).To(Equal(365 * 24 * time.Hour))
+
+	_, _, err = NewCertificateAuthority(x509.RSA, KeyOptions{}, -time.Hour)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestEncodePrivateKeyPEM(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, keyType := range []x509.PublicKeyAlgorithm{x509.RSA, x509.ECDSA, x509.Ed25519} {
+		key, err := NewPrivateKey(keyType, KeyOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		encoded, err := EncodePrivateKeyPEM(key)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		pkiPath := t.TempDir()
+		g.Expect(WriteKey(pkiPath, "test", key)).To(Succeed())
+		written, err := ioutil.ReadFile(pathForKey(pkiPath, "test"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(written).To(Equal(encoded))
+
+		parsed, err := ParsePrivateKey(encoded)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(parsed.Public()).To(Equal(key.Public()))
+	}
+}
+
+func TestWriteCertAndKeyFileMode(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	pkiPath := filepath.Join(t.TempDir(), "pki")
+
+	// an existing world-readable key is replaced by a private one
+	g.Expect(os.MkdirAll(pkiPath, 0755)).To(Succeed())
+	g.Expect(ioutil.WriteFile(pathForKey(pkiPath, "ca"), []byte("stale"), 0644)).To(Succeed())
+
+	g.Expect(WriteCertAndKey(pkiPath, "ca", caCert, caKey)).To(Succeed())
+
+	info, err := os.Stat(pathForKey(pkiPath, "ca"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+	info, err = os.Stat(pathForCert(pkiPath, "ca"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0644)))
+
+	keyData, err := ioutil.ReadFile(pathForKey(pkiPath, "ca"))
+	g.Expect(err).ToNot(HaveOccurred())
+	_, _, err = ParseCertAndKey(EncodeCertPEM(caCert), keyData)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// no temporary file is left
+	entries, err := ioutil.ReadDir(pkiPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(2))
+}
+
+func TestEd25519Key(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key, err := NewPrivateKey(x509.Ed25519, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key).To(BeAssignableToTypeOf(ed25519.PrivateKey{}))
+
+	pkiPath := t.TempDir()
+	g.Expect(WriteKey(pkiPath, "test", key)).To(Succeed())
+	data, err := ioutil.ReadFile(pathForKey(pkiPath, "test"))
+	g.Expect(err).ToNot(HaveOccurred())
+	parsed, err := ParsePrivateKey(data)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(parsed).To(Equal(key))
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	cert, err := NewSignedCert(key, caCert, caKey, false, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.PublicKeyAlgorithm).To(Equal(x509.Ed25519))
+	g.Expect(cert.KeyUsage & x509.KeyUsageKeyEncipherment).To(BeZero())
+	g.Expect(cert.KeyUsage & x509.KeyUsageDigitalSignature).ToNot(BeZero())
+
+	// an Ed25519 CA could sign certs too
+	edCACert, edCAKey, err := NewCertificateAuthority(x509.Ed25519, KeyOptions{}, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	cert, _, err = NewCertAndKey(edCACert, edCAKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.SignatureAlgorithm).To(Equal(x509.PureEd25519))
+	_, err = VerifyAgainstAny(cert, edCACert)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestCertExpiry(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	cert, _, err := NewCertAndKey(caCert, caKey, CertOptions{Validity: 10 * 24 * time.Hour})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	remaining := CertTimeRemaining(cert)
+	g.Expect(remaining).To(BeNumerically("<=", 10*24*time.Hour))
+	g.Expect(remaining).To(BeNumerically(">", 10*24*time.Hour-time.Minute))
+	g.Expect(CertExpiresWithin(cert, 30*24*time.Hour)).To(BeTrue())
+	g.Expect(CertExpiresWithin(cert, 24*time.Hour)).To(BeFalse())
+
+	expired := &x509.Certificate{NotAfter: time.Now().Add(-time.Hour)}
+	g.Expect(CertTimeRemaining(expired)).To(BeNumerically("<", 0))
+	g.Expect(CertExpiresWithin(expired, 0)).To(BeTrue())
+
+	pkiPath := t.TempDir()
+	g.Expect(WriteCert(pkiPath, ChaosdPkiName, cert)).To(Succeed())
+	loaded, err := LoadCertFromPath(pkiPath, ChaosdPkiName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(loaded.Equal(cert)).To(BeTrue())
+
+	_, err = LoadCertFromPath(pkiPath, "missing")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestRSAKeySize(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key, err := NewPrivateKey(x509.RSA, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.(*rsa.PrivateKey).N.BitLen()).To(Equal(2048))
+
+	key, err = NewPrivateKey(x509.RSA, KeyOptions{RSAKeySize: 3072})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.(*rsa.PrivateKey).N.BitLen()).To(Equal(3072))
+
+	pkiPath := t.TempDir()
+	g.Expect(WriteKey(pkiPath, "test", key)).To(Succeed())
+	data, err := ioutil.ReadFile(pathForKey(pkiPath, "test"))
+	g.Expect(err).ToNot(HaveOccurred())
+	parsed, err := ParsePrivateKey(data)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(parsed.(*rsa.PrivateKey).N.BitLen()).To(Equal(3072))
+	g.Expect(parsed.Public()).To(Equal(key.Public()))
+
+	_, err = NewPrivateKey(x509.RSA, KeyOptions{RSAKeySize: 1024})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("minimum"))
+	_, err = NewPrivateKey(x509.RSA, KeyOptions{RSAKeySize: 2049})
+	g.Expect(err).To(HaveOccurred())
+
+	// the RSA key size is ignored by other key types
+	key, err = NewPrivateKey(x509.ECDSA, KeyOptions{RSAKeySize: 1024})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key).To(BeAssignableToTypeOf(&ecdsa.PrivateKey{}))
+	key, err = NewPrivateKey(x509.Ed25519, KeyOptions{RSAKeySize: 1024})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key).To(BeAssignableToTypeOf(ed25519.PrivateKey{}))
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	cert, _, err := NewCertAndKey(caCert, caKey, CertOptions{Key: KeyOptions{RSAKeySize: 3072}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.PublicKey.(*rsa.PublicKey).N.BitLen()).To(Equal(3072))
+}
+
+func TestGenerateSignedCertPEM(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	ips, err := ParseIPAddresses([]string{"10.0.3.17"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	certPEM, keyPEM, err := GenerateSignedCertPEM(caCert, caKey, CertOptions{
+		Subject:     pkix.Name{CommonName: "host-1.example.org"},
+		DNSNames:    []string{"host-1.example.org"},
+		IPAddresses: ips,
+		Validity:    24 * time.Hour,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	cert, key, err := ParseCertAndKey(certPEM, keyPEM)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.Subject.CommonName).To(Equal("host-1.example.org"))
+	g.Expect(cert.DNSNames).To(ContainElement("host-1.example.org"))
+	g.Expect(cert.VerifyHostname("10.0.3.17")).To(Succeed())
+	g.Expect(cert.NotAfter.Sub(cert.NotBefore)).To(Equal(24 * time.Hour))
+	g.Expect(CertMatchesKey(cert, key)).To(Succeed())
+	g.Expect(VerifyCert(cert, caCert)).To(Succeed())
+
+	_, _, err = GenerateSignedCertPEM(caCert, caKey, CertOptions{Validity: -time.Hour})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestECDSACurve(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key, err := NewPrivateKey(x509.ECDSA, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.(*ecdsa.PrivateKey).Curve).To(Equal(elliptic.P256()))
+
+	_, err = NewPrivateKey(x509.ECDSA, KeyOptions{Curve: elliptic.P224()})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("P-224"))
+
+	key, err = NewPrivateKey(x509.ECDSA, KeyOptions{Curve: elliptic.P521()})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.(*ecdsa.PrivateKey).Curve).To(Equal(elliptic.P521()))
+
+	caCert, caKey, err := NewCertificateAuthority(x509.ECDSA, KeyOptions{Curve: elliptic.P384()}, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(caCert.PublicKey.(*ecdsa.PublicKey).Curve).To(Equal(elliptic.P384()))
+
+	key, err = NewPrivateKey(x509.ECDSA, KeyOptions{Curve: elliptic.P384()})
+	g.Expect(err).ToNot(HaveOccurred())
+	cert, err := NewSignedCert(key, caCert, caKey, false, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	pkiPath := t.TempDir()
+	g.Expect(WriteCertAndKey(pkiPath, "test", cert, key)).To(Succeed())
+	parsedCert, err := LoadCertFromPath(pkiPath, "test")
+	g.Expect(err).ToNot(HaveOccurred())
+	keyData, err := ioutil.ReadFile(pathForKey(pkiPath, "test"))
+	g.Expect(err).ToNot(HaveOccurred())
+	parsedKey, err := ParsePrivateKey(keyData)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(parsedKey.(*ecdsa.PrivateKey).Curve).To(Equal(elliptic.P384()))
+	g.Expect(CertMatchesKey(parsedCert, parsedKey)).To(Succeed())
+	g.Expect(VerifyCert(parsedCert, caCert)).To(Succeed())
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// TLSSecretBundle is the certificates carried by a Kubernetes TLS Secret
+type TLSSecretBundle struct {
+	// Leaf is the first certificate of tls.crt
+	Leaf *x509.Certificate
+	// Intermediates is the rest of the chain in tls.crt, ordered from the leaf to the root
+	Intermediates []*x509.Certificate
+	// Roots is the certificates in ca.crt
+	Roots []*x509.Certificate
+
+	// IntermediatePool and RootPool are ready to be used in x509.VerifyOptions
+	IntermediatePool *x509.CertPool
+	RootPool         *x509.CertPool
+}
+
+// ParseTLSSecretFull parses the leaf and the intermediate chain from tls.crt, and the roots from ca.crt
+// of a Kubernetes TLS Secret
+func ParseTLSSecretFull(secret *v1.Secret) (*TLSSecretBundle, error) {
+	tlsCertBytes, ok := secret.Data[v1.TLSCertKey]
+	if !ok {
+		return nil, errors.Errorf("could not found %s in secret %s/%s", v1.TLSCertKey, secret.Namespace, secret.Name)
+	}
+	caCertBytes, ok := secret.Data["ca.crt"]
+	if !ok {
+		return nil, errors.Errorf("could not found ca.crt in secret %s/%s", secret.Namespace, secret.Name)
+	}
+
+	tlsCerts, err := ParseCerts(tlsCertBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s failed", v1.TLSCertKey)
+	}
+	chain, err := OrderChain(tlsCerts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid chain in %s", v1.TLSCertKey)
+	}
+
+	roots, err := ParseCerts(caCertBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse ca.crt failed")
+	}
+
+	bundle := &TLSSecretBundle{
+		Leaf:             chain[0],
+		Intermediates:    chain[1:],
+		Roots:            roots,
+		IntermediatePool: x509.NewCertPool(),
+		RootPool:         x509.NewCertPool(),
+	}
+	for _, cert := range bundle.Intermediates {
+		bundle.IntermediatePool.AddCert(cert)
+	}
+	for _, cert := range bundle.Roots {
+		bundle.RootPool.AddCert(cert)
+	}
+	return bundle, nil
+}
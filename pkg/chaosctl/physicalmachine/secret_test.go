@@ -0,0 +1,63 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/x509"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseTLSSecretFull(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	root, rootKey := newTestCA(g, "root")
+	intermediate, intermediateKey := newTestCert(g, "intermediate", true, root, rootKey)
+	leaf, _ := newTestCert(g, "leaf", false, intermediate, intermediateKey)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "chaos-testing",
+			Name:      "chaosd-tls",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			v1.TLSCertKey: ConcatPEM(EncodeCertPEM(leaf), EncodeCertPEM(intermediate)),
+			"ca.crt":      EncodeCertPEM(root),
+		},
+	}
+
+	bundle, err := ParseTLSSecretFull(secret)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(bundle.Leaf.Equal(leaf)).To(BeTrue())
+	g.Expect(bundle.Intermediates).To(HaveLen(1))
+	g.Expect(bundle.Intermediates[0].Equal(intermediate)).To(BeTrue())
+	g.Expect(bundle.Roots).To(HaveLen(1))
+	g.Expect(bundle.Roots[0].Equal(root)).To(BeTrue())
+
+	_, err = bundle.Leaf.Verify(x509.VerifyOptions{
+		Roots:         bundle.RootPool,
+		Intermediates: bundle.IntermediatePool,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	delete(secret.Data, "ca.crt")
+	_, err = ParseTLSSecretFull(secret)
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// spiffeX509SVIDUse is the "use" of the JWKs which are X.509 SVID authorities
+const spiffeX509SVIDUse = "x509-svid"
+
+// spiffeBundle is the SPIFFE trust bundle, which is a JWK set,
+// the trust domain is not a part of it but comes from the context the bundle is used in
+type spiffeBundle struct {
+	Keys []spiffeJWK `json:"keys"`
+}
+
+type spiffeJWK struct {
+	Use string   `json:"use"`
+	Kty string   `json:"kty"`
+	Crv string   `json:"crv,omitempty"`
+	X   string   `json:"x,omitempty"`
+	Y   string   `json:"y,omitempty"`
+	N   string   `json:"n,omitempty"`
+	E   string   `json:"e,omitempty"`
+	X5c []string `json:"x5c"`
+}
+
+// ToSPIFFEBundle exports the CA certificates as the standard SPIFFE trust bundle of the trust domain,
+// with one X.509 SVID authority key for each CA. The trust domain is a bare name like "example.org"
+// without the "spiffe://" prefix, it's validated but not written into the bundle as the format has no member for it.
+func ToSPIFFEBundle(trustDomain string, cas ...*x509.Certificate) ([]byte, error) {
+	if err := validateTrustDomain(trustDomain); err != nil {
+		return nil, err
+	}
+	if len(cas) == 0 {
+		return nil, errors.New("no CA to export")
+	}
+
+	var bundle spiffeBundle
+	for _, ca := range cas {
+		key, err := newSPIFFEJWK(ca)
+		if err != nil {
+			return nil, errors.Wrapf(err, "export CA %q failed", ca.Subject.CommonName)
+		}
+		bundle.Keys = append(bundle.Keys, key)
+	}
+	return json.MarshalIndent(bundle, "", "  ")
+}
+
+func newSPIFFEJWK(cert *x509.Certificate) (spiffeJWK, error) {
+	key := spiffeJWK{
+		Use: spiffeX509SVIDUse,
+		X5c: []string{base64.StdEncoding.EncodeToString(cert.Raw)},
+	}
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		key.Kty = "EC"
+		key.Crv = pub.Curve.Params().Name
+		key.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		key.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		key.Kty = "OKP"
+		key.Crv = "Ed25519"
+		key.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return key, errors.Errorf("unsupported public key type %T", pub)
+	}
+	return key, nil
+}
+
+// validateTrustDomain checks the trust domain name, which consists of lowercase letters, digits, dots, dashes and underscores
+func validateTrustDomain(trustDomain string) error {
+	if len(trustDomain) == 0 {
+		return errors.New("trust domain cannot be empty")
+	}
+	if strings.Contains(trustDomain, "://") {
+		return errors.Errorf("trust domain %q must be a bare name without the scheme", trustDomain)
+	}
+	for _, c := range trustDomain {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return errors.Errorf("trust domain %q contains invalid character %q", trustDomain, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestToSPIFFEBundle(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	oldCA, _ := newTestCA(g, "old-ca")
+	newCA, _ := newTestCA(g, "new-ca")
+
+	data, err := ToSPIFFEBundle("chaos-mesh.org", oldCA, newCA)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// only the standard members are written
+	var members map[string]json.RawMessage
+	g.Expect(json.Unmarshal(data, &members)).To(Succeed())
+	g.Expect(members).To(HaveLen(1))
+	g.Expect(members).To(HaveKey("keys"))
+
+	var bundle spiffeBundle
+	g.Expect(json.Unmarshal(data, &bundle)).To(Succeed())
+	g.Expect(bundle.Keys).To(HaveLen(2))
+
+	cas := []*x509.Certificate{oldCA, newCA}
+	for i, key := range bundle.Keys {
+		g.Expect(key.Use).To(Equal("x509-svid"))
+		g.Expect(key.Kty).To(Equal("EC"))
+		g.Expect(key.Crv).To(Equal("P-256"))
+		g.Expect(key.X).ToNot(BeEmpty())
+		g.Expect(key.Y).ToNot(BeEmpty())
+		g.Expect(key.X5c).To(HaveLen(1))
+
+		der, err := base64.StdEncoding.DecodeString(key.X5c[0])
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(der).To(Equal(cas[i].Raw))
+	}
+
+	for _, trustDomain := range []string{"", "spiffe://chaos-mesh.org", "Chaos-Mesh.org", "chaos-mesh.org/path"} {
+		_, err = ToSPIFFEBundle(trustDomain, oldCA)
+		g.Expect(err).To(HaveOccurred(), trustDomain)
+	}
+	_, err = ToSPIFFEBundle("chaos-mesh.org")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -93,7 +93,7 @@ func (s *SshTunnel) Close() error {
 	return s.client.Close()
 }
 
-func (s *SshTunnel) SFTP(filename string, data []byte) error {
+func (s *SshTunnel) SFTP(filename string, data []byte, perm os.FileMode) error {
 	if s.client == nil {
 		return errors.New("tunnel is not opened")
 	}
@@ -115,6 +115,9 @@ func (s *SshTunnel) SFTP(filename string, data []byte) error {
 	}
 	defer f.Close()
 
+	if err := f.Chmod(perm); err != nil {
+		return errors.Wrapf(err, "chmod file %s failed", filename)
+	}
 	if _, err := f.Write(data); err != nil {
 		return errors.Wrapf(err, "write file %s failed", filename)
 	}
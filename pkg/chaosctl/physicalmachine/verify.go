@@ -0,0 +1,140 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RequireExtensions checks that the certificate carries all the required extensions,
+// the returned error lists the OIDs of the missing ones
+func RequireExtensions(cert *x509.Certificate, requiredOIDs []asn1.ObjectIdentifier) error {
+	var missing []string
+	for _, oid := range requiredOIDs {
+		found := false
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(oid) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, oid.String())
+		}
+	}
+
+	if len(missing) != 0 {
+		return errors.Errorf("certificate %q is missing required extensions: %s",
+			cert.Subject.CommonName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// VerifyAgainstAny verifies the certificate against each of the candidate CAs in order,
+// and returns the first CA which the certificate chains to
+func VerifyAgainstAny(cert *x509.Certificate, cas ...*x509.Certificate) (matched *x509.Certificate, err error) {
+	if len(cas) == 0 {
+		return nil, errors.New("no candidate CA to verify against")
+	}
+
+	var failures []string
+	for _, ca := range cas {
+		if err := verifyWithRoot(cert, ca); err != nil {
+			failures = append(failures, fmt.Sprintf("%q: %v", ca.Subject.CommonName, err))
+			continue
+		}
+		return ca, nil
+	}
+
+	return nil, errors.Errorf("certificate %q does not chain to any candidate CA: %s",
+		cert.Subject.CommonName, strings.Join(failures, "; "))
+}
+
+// IsSelfSigned reports whether the certificate is issued by itself,
+// which means the subject equals the issuer and the signature is verified by its own public key
+func IsSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+		return false
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// VerifyCert verifies that the certificate chains to the CA, is valid at present,
+// and is allowed to be used for digital signature
+func VerifyCert(cert *x509.Certificate, caCert *x509.Certificate) error {
+	now := time.Now()
+	if now.Before(cert.NotBefore) {
+		return errors.Errorf("certificate %q is not valid until %s", cert.Subject.CommonName, cert.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(cert.NotAfter) {
+		return errors.Errorf("certificate %q has expired at %s", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+	}
+	if cert.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return errors.Errorf("certificate %q is not allowed to be used for digital signature", cert.Subject.CommonName)
+	}
+
+	if err := verifyWithRoot(cert, caCert); err != nil {
+		return errors.Wrapf(err, "certificate %q does not chain to CA %q", cert.Subject.CommonName, caCert.Subject.CommonName)
+	}
+	return nil
+}
+
+// CertMatchesKey checks that the public key of the certificate is the public part of the private key
+func CertMatchesKey(cert *x509.Certificate, key crypto.Signer) error {
+	pub, ok := cert.PublicKey.(interface {
+		Equal(crypto.PublicKey) bool
+	})
+	if !ok {
+		return errors.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+	if !pub.Equal(key.Public()) {
+		return errors.Errorf("the private key does not match the public key of certificate %q", cert.Subject.CommonName)
+	}
+	return nil
+}
+
+// checkSigningCA checks that the CA certificate is allowed to sign certificates and matches the CA key
+func checkSigningCA(caCert *x509.Certificate, caKey crypto.Signer) error {
+	if caCert == nil || caKey == nil {
+		return errors.New("CA certificate and key cannot be nil")
+	}
+	if !caCert.IsCA {
+		return errors.Errorf("certificate %q is not a CA", caCert.Subject.CommonName)
+	}
+	if caCert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return errors.Errorf("CA %q is not allowed to sign certificates", caCert.Subject.CommonName)
+	}
+	return CertMatchesKey(caCert, caKey)
+}
+
+// verifyWithRoot verifies that the certificate chains to the root certificate
+func verifyWithRoot(cert, root *x509.Certificate) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package physicalmachine
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRequireExtensions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, _ := newTestCA(g, "test-ca")
+
+	oidKeyUsage := asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidBasicConstraints := asn1.ObjectIdentifier{2, 5, 29, 19}
+	oidCRLDistributionPoints := asn1.ObjectIdentifier{2, 5, 29, 31}
+
+	g.Expect(RequireExtensions(caCert, []asn1.ObjectIdentifier{oidKeyUsage, oidBasicConstraints})).To(Succeed())
+
+	err := RequireExtensions(caCert, []asn1.ObjectIdentifier{oidKeyUsage, oidCRLDistributionPoints, oidBasicConstraints})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("2.5.29.31"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("2.5.29.15"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("2.5.29.19"))
+}
+
+func TestVerifyAgainstAny(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	oldCA, oldCAKey := newTestCA(g, "old-ca")
+	newCA, _ := newTestCA(g, "new-ca")
+	otherCA, _ := newTestCA(g, "other-ca")
+	leaf, _ := newTestCert(g, "leaf", false, oldCA, oldCAKey)
+
+	matched, err := VerifyAgainstAny(leaf, newCA, oldCA)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(matched).To(Equal(oldCA))
+
+	_, err = VerifyAgainstAny(leaf, newCA, otherCA)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("new-ca"))
+	g.Expect(err.Error()).To(ContainSubstring("other-ca"))
+
+	_, err = VerifyAgainstAny(leaf)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestIsSelfSigned(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	g.Expect(IsSelfSigned(caCert)).To(BeTrue())
+
+	leaf, _ := newTestCert(g, "leaf", false, caCert, caKey)
+	g.Expect(IsSelfSigned(leaf)).To(BeFalse())
+
+	// same subject as the CA, but signed by the CA key instead of its own
+	impostor, _ := newTestCert(g, "test-ca", false, caCert, caKey)
+	g.Expect(IsSelfSigned(impostor)).To(BeFalse())
+}
+
+func TestVerifyCert(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	otherCA, _ := newTestCA(g, "other-ca")
+
+	cert, key, err := NewCertAndKey(caCert, caKey, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(VerifyCert(cert, caCert)).To(Succeed())
+
+	err = VerifyCert(cert, otherCA)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("other-ca"))
+
+	expired := *cert
+	expired.NotAfter = time.Now().Add(-time.Hour)
+	err = VerifyCert(&expired, caCert)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("expired"))
+
+	noSignature := *cert
+	noSignature.KeyUsage = x509.KeyUsageKeyEncipherment
+	err = VerifyCert(&noSignature, caCert)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("digital signature"))
+
+	g.Expect(CertMatchesKey(cert, key)).To(Succeed())
+	g.Expect(CertMatchesKey(caCert, caKey)).To(Succeed())
+	err = CertMatchesKey(cert, caKey)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("does not match"))
+}
+
+func TestNewSignedCertChecksCA(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caCert, caKey := newTestCA(g, "test-ca")
+	otherCACert, otherCAKey := newTestCA(g, "other-ca")
+	leaf, leafKey := newTestCert(g, "test-leaf", false, caCert, caKey)
+
+	key, err := NewPrivateKey(x509.ECDSA, KeyOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = NewSignedCert(key, leaf, leafKey, false, CertOptions{})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("not a CA"))
+	_, _, err = NewCertAndKey(leaf, leafKey, CertOptions{})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("not a CA"))
+
+	_, err = NewSignedCert(key, caCert, otherCAKey, false, CertOptions{})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("does not match"))
+	_, _, err = NewCertAndKey(otherCACert, caKey, CertOptions{})
+	g.Expect(err).To(HaveOccurred())
+
+	// a CA without the certificate signing key usage
+	noCertSign := *caCert
+	noCertSign.KeyUsage = x509.KeyUsageDigitalSignature
+	_, err = NewSignedCert(key, &noCertSign, caKey, false, CertOptions{})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("not allowed to sign certificates"))
+
+	_, err = NewSignedCert(key, nil, nil, false, CertOptions{})
+	g.Expect(err).To(HaveOccurred())
+
+	cert, err := NewSignedCert(key, caCert, caKey, false, CertOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(VerifyCert(cert, caCert)).To(Succeed())
+}